@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/wahyurudiyan/go-otel-context-propagation/pkg/telemetry"
+	"github.com/wahyurudiyan/go-otel-context-propagation/pkg/telemetry/telemetrytest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestPushNotificationEndToEnd(t *testing.T) {
+	recorder := telemetrytest.Install(t, "notification-e2e-test")
+	serverURL, grpcAddr := startNotificationServer(t)
+	app := newTestApp(t, serverURL, grpcAddr)
+
+	postJSON(t, app, "/client/notifications/push", `{"user_id":42,"title":"t","body":"b","data":{"campaign":"spring","token":"secret"}}`, fiber.StatusOK)
+
+	spans := recorder.Spans()
+	controller := recorder.Span(t, "controller:PushNotification")
+	handler := recorder.Span(t, "handler:SendPushNotification")
+	server := recorder.Span(t, "grpcHandler:SendPushNotification")
+
+	telemetrytest.AssertSameTrace(t, spans...)
+	telemetrytest.AssertChildOf(t, controller, handler)
+	telemetrytest.AssertChain(t, spans,
+		"controller:PushNotification",
+		"handler:SendPushNotification",
+		"notification.NotificationService/SendPushNotification",
+		"grpcHandler:SendPushNotification",
+	)
+	telemetrytest.AssertAttributes(t, server, attribute.String("notification.data.campaign", "spring"))
+	// Personal data and data keys that are not allowed are never recorded.
+	assertNoAttributes(t, server, "user.id", "notification.data.token")
+	for _, span := range spans {
+		telemetrytest.AssertStatus(t, span, codes.Unset)
+	}
+
+	assertLogged(t, recorder, "grpc.SendPushNotification: span info", handler)
+	assertCounted(t, recorder, "notification.sent", 2)
+}
+
+func TestEmailNotificationEndToEnd(t *testing.T) {
+	recorder := telemetrytest.Install(t, "notification-e2e-test")
+	serverURL, grpcAddr := startNotificationServer(t)
+	app := newTestApp(t, serverURL, grpcAddr)
+
+	postJSON(t, app, "/client/notifications/email", `{"user_id":42,"email":"jane@example.com","title":"t","body":"b"}`, fiber.StatusOK)
+
+	spans := recorder.Spans()
+	controller := recorder.Span(t, "controller:EmailNotification")
+	handler := recorder.Span(t, "handler:SendEmailNotification")
+	server := recorder.Span(t, "httpHandler:SendEmailNotification")
+
+	telemetrytest.AssertSameTrace(t, spans...)
+	telemetrytest.AssertChildOf(t, controller, handler)
+	telemetrytest.AssertChain(t, spans,
+		"controller:EmailNotification",
+		"handler:SendEmailNotification",
+		"/server/notifications/email",
+		"httpHandler:SendEmailNotification",
+	)
+	assertNoAttributes(t, server, "user.id", "email.to")
+	for _, span := range spans {
+		telemetrytest.AssertStatus(t, span, codes.Unset)
+	}
+
+	assertLogged(t, recorder, "http.SendEmailNotification: span info", handler)
+	assertCounted(t, recorder, "notification.sent", 2)
+}
+
+func TestEmailNotificationServerUnavailable(t *testing.T) {
+	recorder := telemetrytest.Install(t, "notification-e2e-test")
+	_, grpcAddr := startNotificationServer(t)
+
+	// Nothing listens on port 1, the outgoing request fails.
+	app := newTestApp(t, "http://127.0.0.1:1", grpcAddr)
+
+	postJSON(t, app, "/client/notifications/email", `{"user_id":42,"email":"jane@example.com"}`, fiber.StatusInternalServerError)
+
+	controller := recorder.Span(t, "controller:EmailNotification")
+	handler := recorder.Span(t, "handler:SendEmailNotification")
+
+	telemetrytest.AssertChildOf(t, controller, handler)
+	telemetrytest.AssertStatus(t, handler, codes.Error)
+	telemetrytest.AssertStatus(t, controller, codes.Error)
+	if spans := recorder.SpansByName("httpHandler:SendEmailNotification"); len(spans) != 0 {
+		t.Errorf("server handled %d requests, want none", len(spans))
+	}
+	assertCounted(t, recorder, "notification.failed", 1)
+}
+
+func TestBaggageReachesServerTelemetry(t *testing.T) {
+	recorder := telemetrytest.Install(t, "notification-e2e-test",
+		telemetry.WithBaggagePromotion(telemetry.BaggageConfig{
+			AllowedKeys: []string{BaggageTenantID, BaggageUserID, BaggageNotificationType},
+		}),
+		telemetry.WithRedactionRules(telemetry.DefaultRedactionRules...),
+	)
+	serverURL, grpcAddr := startNotificationServer(t)
+	app := newTestApp(t, serverURL, grpcAddr)
+
+	req := httptest.NewRequest("POST", "/client/notifications/push", strings.NewReader(`{"user_id":42,"title":"t","body":"b"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TenantIDHeader, "acme")
+	req.Header.Set(OriginHeader, "mobile")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("push: status %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+
+	// The origin is propagated but not allowed, the user ID is hashed.
+	want := map[string]string{
+		"baggage.tenant_id":         "acme",
+		"baggage.user_id":           telemetry.HashValue("42"),
+		"baggage.notification_type": "push",
+	}
+	server := recorder.Span(t, "grpcHandler:SendPushNotification")
+	got := map[string]string{}
+	for _, attr := range server.Attributes() {
+		if strings.HasPrefix(string(attr.Key), "baggage.") {
+			got[string(attr.Key)] = attr.Value.Emit()
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("server span baggage = %v, want %v", got, want)
+	}
+
+	for _, record := range recorder.Logs() {
+		if record.Body().AsString() != "grpc.SendPushNotification: span info" {
+			continue
+		}
+		got := map[string]string{}
+		record.WalkAttributes(func(attr otellog.KeyValue) bool {
+			if strings.HasPrefix(attr.Key, "baggage.") {
+				got[attr.Key] = attr.Value.AsString()
+			}
+			return true
+		})
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("server log baggage = %v, want %v", got, want)
+		}
+		return
+	}
+	t.Errorf("no server log recorded")
+}
+
+// assertNoAttributes checks that span has none of keys.
+func assertNoAttributes(t *testing.T, span sdktrace.ReadOnlySpan, keys ...attribute.Key) {
+	t.Helper()
+
+	for _, attr := range span.Attributes() {
+		for _, key := range keys {
+			if attr.Key == key {
+				t.Errorf("span %q has attribute %s=%s", span.Name(), attr.Key, attr.Value.Emit())
+			}
+		}
+	}
+}
+
+func postJSON(t *testing.T, app *fiber.App, route, body string, wantStatus int) {
+	t.Helper()
+
+	req := httptest.NewRequest("POST", route, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s: %v", route, err)
+	}
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("%s: status %d, want %d", route, resp.StatusCode, wantStatus)
+	}
+}
+
+// assertLogged checks that a record with body was emitted within span.
+func assertLogged(t *testing.T, recorder *telemetrytest.Recorder, body string, span sdktrace.ReadOnlySpan) {
+	t.Helper()
+
+	for _, record := range recorder.Logs() {
+		if record.Body().AsString() == body {
+			telemetrytest.AssertLogInSpan(t, record, span)
+			return
+		}
+	}
+	t.Errorf("no log %q recorded", body)
+}
+
+// assertCounted checks the sum of the counter name over all its data points,
+// recorded here by both the client and the server.
+func assertCounted(t *testing.T, recorder *telemetrytest.Recorder, name string, want int64) {
+	t.Helper()
+
+	var got int64
+	for _, sm := range recorder.Metrics(t).ScopeMetrics {
+		for _, m := range sm.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if m.Name != name || !ok {
+				continue
+			}
+			for _, point := range sum.DataPoints {
+				got += point.Value
+			}
+		}
+	}
+	if got != want {
+		t.Errorf("%s = %d, want %d", name, got, want)
+	}
+}
+
+func TestLatencyHistogramsCarryExemplars(t *testing.T) {
+	recorder := telemetrytest.Install(t, "notification-e2e-test")
+	serverURL, grpcAddr := startNotificationServer(t)
+	app := newTestApp(t, serverURL, grpcAddr)
+
+	postJSON(t, app, "/client/notifications/push", `{"user_id":42,"title":"t","body":"b"}`, fiber.StatusOK)
+
+	traceID := recorder.Span(t, "controller:PushNotification").SpanContext().TraceID()
+	for _, name := range []string{"notification.send.duration", "http.server.duration", "rpc.server.duration"} {
+		histogram, ok := recorder.Metric(t, name).Data.(metricdata.Histogram[float64])
+		if !ok {
+			t.Errorf("%s is not a float64 histogram", name)
+			continue
+		}
+
+		var linked bool
+		for _, point := range histogram.DataPoints {
+			for _, exemplar := range point.Exemplars {
+				linked = linked || bytes.Equal(exemplar.TraceID, traceID[:])
+			}
+		}
+		if !linked {
+			t.Errorf("%s has no exemplar of trace %s", name, traceID)
+		}
+	}
+}
@@ -10,13 +10,17 @@ import (
 	"strconv"
 
 	"github.com/wahyurudiyan/go-otel-context-propagation/contract/notificationpb"
+	"github.com/wahyurudiyan/go-otel-context-propagation/pkg/notificationmetrics"
 	"github.com/wahyurudiyan/go-otel-context-propagation/pkg/telemetry"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
 )
 
 type handler struct {
 	httpClient      *http.Client
+	serverURL       string
 	notificationCli notificationpb.NotificationServiceClient
+	metrics         *notificationmetrics.Metrics
 }
 
 type Handler interface {
@@ -26,23 +30,23 @@ type Handler interface {
 
 func NewNotificationHandler(
 	httpClient *http.Client,
+	serverURL string,
 	notificationCli notificationpb.NotificationServiceClient,
 ) Handler {
 	return &handler{
 		httpClient:      httpClient,
+		serverURL:       serverURL,
 		notificationCli: notificationCli,
+		metrics:         newNotificationMetrics(),
 	}
 }
 
-func (h *handler) SendPushNotification(ctx context.Context, data PushNotificationRequest) error {
+func (h *handler) SendPushNotification(ctx context.Context, data PushNotificationRequest) (err error) {
 	ctx, span := telemetry.StartSpan(ctx, "handler:SendPushNotification")
 	defer span.End()
 
-	spanCtx := span.SpanContext()
-	zap.L().Info("http.SendPushNotification: span info",
-		zap.String("span.id", spanCtx.SpanID().String()),
-		zap.String("trace.id", spanCtx.TraceID().String()),
-	)
+	logger := telemetry.Logger(ctx)
+	logger.Info("grpc.SendPushNotification: span info")
 
 	rpcReq := &notificationpb.PushNotificationRequest{
 		Data:   data.Data,
@@ -50,33 +54,41 @@ func (h *handler) SendPushNotification(ctx context.Context, data PushNotificatio
 		Title:  data.Title,
 		UserId: strconv.Itoa(int(data.UserId)),
 	}
+
+	done := h.metrics.Start(ctx, notificationmetrics.ChannelPush, notificationmetrics.TransportGRPC, proto.Size(rpcReq))
+	defer func() { done(err) }()
+
 	rpcRes, err := h.notificationCli.SendPushNotification(ctx, rpcReq)
 	if err != nil {
-		zap.L().Error("failed to call rpc SendPushNotification", zap.Error(err))
+		telemetry.RecordError(span, err)
+		logger.Error("failed to call rpc SendPushNotification", zap.Error(err))
 		return err
 	}
 
-	zap.L().Debug("RPC payload response", zap.Any("grpc.response", rpcRes))
+	logger.Debug("RPC payload response",
+		zap.Bool("grpc.response.success", rpcRes.GetSuccess()),
+		zap.String("grpc.response.message", rpcRes.GetMessage()),
+	)
 
 	return nil
 }
 
-func (h *handler) SendEmailNotification(ctx context.Context, data EmailNotificationRequest) ([]byte, error) {
+func (h *handler) SendEmailNotification(ctx context.Context, data EmailNotificationRequest) (_ []byte, err error) {
 	ctx, span := telemetry.StartSpan(ctx, "handler:SendEmailNotification")
 	defer span.End()
 
-	spanCtx := span.SpanContext()
-	zap.L().Info("http.SendPushNotification: span info",
-		zap.String("span.id", spanCtx.SpanID().String()),
-		zap.String("trace.id", spanCtx.TraceID().String()),
-	)
+	logger := telemetry.Logger(ctx)
+	logger.Info("http.SendEmailNotification: span info")
 
 	requestBody, err := json.Marshal(data)
 	if err != nil {
 		return nil, err
 	}
 
-	notificationServiceURL := "http://localhost:8080/server/notifications/email"
+	done := h.metrics.Start(ctx, notificationmetrics.ChannelEmail, notificationmetrics.TransportHTTP, len(requestBody))
+	defer func() { done(err) }()
+
+	notificationServiceURL := h.serverURL + "/server/notifications/email"
 	httpRequest, err := http.NewRequestWithContext(
 		ctx,
 		"POST", notificationServiceURL,
@@ -89,19 +101,23 @@ func (h *handler) SendEmailNotification(ctx context.Context, data EmailNotificat
 	httpRequest.Header.Add("Content-Type", "application/json")
 	resp, err := h.httpClient.Do(httpRequest)
 	if err != nil {
+		telemetry.RecordError(span, err)
 		return nil, err
 	}
+	defer resp.Body.Close()
+
+	telemetry.SetHTTPStatus(span, resp.StatusCode)
 	if resp.StatusCode >= 400 {
 		return nil, fmt.Errorf("http call error occur: %s", resp.Status)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
+		telemetry.RecordError(span, err)
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	zap.L().Debug("HTTP payload response", zap.ByteString("grpc.response", requestBody))
+	logger.Debug("HTTP payload response", zap.Int("http.response.size", len(body)))
 
 	return body, nil
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/contrib/otelfiber/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/wahyurudiyan/go-otel-context-propagation/cmd/client/notification"
+	server "github.com/wahyurudiyan/go-otel-context-propagation/cmd/server/notification"
+	"github.com/wahyurudiyan/go-otel-context-propagation/contract/notificationpb"
+	"github.com/wahyurudiyan/go-otel-context-propagation/pkg/telemetry"
+	"github.com/wahyurudiyan/go-otel-context-propagation/pkg/telemetry/telemetrytest"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	b3TraceID = "463ac35c9f6413ad48485a3953bb6124"
+	b3SpanID  = "a2fb4a1d1a96d312"
+)
+
+func TestB3TraceIsPropagatedToServer(t *testing.T) {
+	t.Setenv("OTEL_PROPAGATORS", "b3,tracecontext,baggage")
+	recorder := telemetrytest.Install(t, "notification-client-test")
+
+	serverURL, grpcAddr := startNotificationServer(t)
+
+	app := newTestApp(t, serverURL, grpcAddr)
+
+	for _, route := range []string{"/client/notifications/push", "/client/notifications/email"} {
+		req := httptest.NewRequest("POST", route, strings.NewReader(`{"user_id":1,"title":"t","body":"b"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-B3-TraceId", b3TraceID)
+		req.Header.Set("X-B3-SpanId", b3SpanID)
+		req.Header.Set("X-B3-Sampled", "1")
+
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s: %v", route, err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("%s: unexpected status %d", route, resp.StatusCode)
+		}
+	}
+
+	spans := recorder.Spans()
+	for _, span := range spans {
+		if got := span.SpanContext().TraceID().String(); got != b3TraceID {
+			t.Errorf("span %q has trace ID %s, want %s", span.Name(), got, b3TraceID)
+		}
+	}
+	telemetrytest.AssertChain(t, spans, "controller:PushNotification", "grpcHandler:SendPushNotification")
+	telemetrytest.AssertChain(t, spans, "controller:EmailNotification", "httpHandler:SendEmailNotification")
+}
+
+// serverData records the campaign key of the notification data, like
+// cmd/server.
+var serverData = server.DataConfig{AllowedKeys: []string{"campaign"}}
+
+// startNotificationServer runs the server's HTTP and gRPC handlers on
+// ephemeral ports, instrumented like cmd/server.
+func startNotificationServer(t *testing.T) (serverURL, grpcAddr string) {
+	t.Helper()
+
+	httpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen HTTP: %v", err)
+	}
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(otelfiber.Middleware(otelfiber.WithoutMetrics(true)))
+	app.Use(telemetry.FiberMetrics("notification-server", nil))
+	app.Post("/server/notifications/email", server.NewNotificationHTTPHandler(serverData).SendEmailNotification())
+	go func() { _ = app.Listener(httpListener) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	grpcListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen gRPC: %v", err)
+	}
+	grpcServer := grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler()))
+	notificationpb.RegisterNotificationServiceServer(grpcServer, server.NewNotificationGRPCHandler(serverData))
+	go func() { _ = grpcServer.Serve(grpcListener) }()
+	t.Cleanup(grpcServer.Stop)
+
+	return "http://" + httpListener.Addr().String(), grpcListener.Addr().String()
+}
+
+// newTestApp returns the client's Fiber app calling the server at serverURL
+// and grpcAddr.
+func newTestApp(t *testing.T, serverURL, grpcAddr string) *fiber.App {
+	t.Helper()
+
+	conn, err := grpc.NewClient(grpcAddr,
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial gRPC server: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return newHTTPApp(notification.NewNotificationHandler(
+		newHTTPClient(0), serverURL, notificationpb.NewNotificationServiceClient(conn),
+	))
+}
@@ -3,108 +3,234 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/contrib/otelfiber/v2"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/wahyurudiyan/go-otel-context-propagation/cmd/client/notification"
 	"github.com/wahyurudiyan/go-otel-context-propagation/contract/notificationpb"
 	"github.com/wahyurudiyan/go-otel-context-propagation/pkg/graceful"
+	"github.com/wahyurudiyan/go-otel-context-propagation/pkg/health"
 	"github.com/wahyurudiyan/go-otel-context-propagation/pkg/telemetry"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
-	"go.opentelemetry.io/otel/attribute"
-	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
-func boostrap(ctx context.Context) graceful.ShutdownCallback {
-	httpServer := startHTTPServer()
-	return func(ctx context.Context) error {
-		if err := httpServer.ShutdownWithContext(ctx); err != nil {
-			return err
-		}
-		return nil
+// components returns the gRPC client connection and the servers, each
+// started after and stopped before the telemetry they report to. Their
+// listeners are bound on start, so a port in use fails the start, and serve
+// errors shut the process down. The readiness turns ready once the HTTP
+// server runs and is the first to stop.
+func components() []graceful.Component {
+	probes := health.New(health.WithShutdownDelay(ReadinessDelay))
+	probes.AddCheck("span-queue", telemetry.SpanQueueCheck(SpanQueueLimit))
+	if limit := exporterFailureLimit(); limit > 0 {
+		probes.AddCheck("telemetry-exporters", telemetry.CheckExporters(limit))
 	}
-}
+	httpDrainer := graceful.NewDrainer("http-server", DrainGracePeriod)
+
+	var (
+		conn          *grpc.ClientConn
+		httpServer    *fiber.App
+		httpListener  net.Listener
+		adminServer   *fiber.App
+		adminListener net.Listener
+	)
 
-func startHTTPServer() *fiber.App {
-	// Init HTTP Server
-	mux := fiber.New()
-	mux.Use(otelfiber.Middleware(otelfiber.WithCustomAttributes(
-		func(ctx *fiber.Ctx) []attribute.KeyValue {
-			return []attribute.KeyValue{
-				semconv.ServiceName("notification:client"),
-			}
+	return []graceful.Component{
+		{
+			Name:      GRPCClientComponent,
+			DependsOn: []string{TelemetryComponent},
+			Start: func(context.Context) (err error) {
+				if conn, err = newGRPCClient(); err != nil {
+					return err
+				}
+				probes.AddCheck(GRPCClientComponent, health.GRPCClientCheck(conn))
+				return nil
+			},
+			Stop: func(context.Context) error {
+				return conn.Close()
+			},
 		},
-	)))
-	router := mux.Group("/client")
+		{
+			Name:      "http-server",
+			DependsOn: []string{TelemetryComponent, GRPCClientComponent},
+			Start: func(context.Context) (err error) {
+				httpServer = newHTTPServer(conn, probes, httpDrainer)
+				if httpListener, err = net.Listen("tcp", HTTPServerAddr); err != nil {
+					return err
+				}
+				httpListener = httpDrainer.Listener(httpListener)
+				zap.L().Info("HTTP server is running!", zap.String("http.address", HTTPServerAddr))
+				return nil
+			},
+			Run: func() error {
+				return httpServer.Listener(httpListener)
+			},
+			Stop: func(ctx context.Context) error {
+				return httpDrainer.Drain(ctx, httpServer.ShutdownWithContext, nil)
+			},
+			StopTimeout: ServerStopTimeout,
+			InFlight:    httpDrainer.InFlight,
+		},
+		{
+			Name:      "admin-server",
+			DependsOn: []string{TelemetryComponent},
+			Start: func(context.Context) (err error) {
+				adminServer = newAdminServer()
+				if adminListener, err = net.Listen("tcp", AdminAddr); err != nil {
+					return err
+				}
+				zap.L().Info("Admin server is running!", zap.String("admin.address", AdminAddr))
+				return nil
+			},
+			Run: func() error {
+				return adminServer.Listener(adminListener)
+			},
+			Stop: func(ctx context.Context) error {
+				return adminServer.ShutdownWithContext(ctx)
+			},
+			StopTimeout: ServerStopTimeout,
+		},
+		{
+			Name:      ReadinessComponent,
+			DependsOn: []string{"http-server"},
+			Start: func(context.Context) error {
+				probes.Start(HealthCheckInterval)
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				return probes.Shutdown(ctx)
+			},
+		},
+	}
+}
 
-	// Init HTTP and GRPC Client
-	httpClient := newHTTPClient(time.Duration(0))
+func newGRPCClient() (*grpc.ClientConn, error) {
 	conn, err := grpc.NewClient(
 		NotificationGRPCHost,
 		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 	)
 	if err != nil {
-		zap.L().Fatal("Cannot listen to gRPC server", zap.String("server.host", NotificationGRPCHost), zap.Error(err))
+		return nil, fmt.Errorf("create gRPC client for %s: %w", NotificationGRPCHost, err)
 	}
+	return conn, nil
+}
+
+func newHTTPServer(conn *grpc.ClientConn, probes *health.Health, drainer *graceful.Drainer) *fiber.App {
+	// Init HTTP and GRPC Client
+	httpClient := newHTTPClient(time.Duration(0))
 	grpcNotificationClient := notificationpb.NewNotificationServiceClient(conn)
 
 	// Init notification logic
-	notificationHandler := notification.NewNotificationHandler(httpClient, grpcNotificationClient)
+	notificationHandler := notification.NewNotificationHandler(httpClient, NotificationHTTPHost, grpcNotificationClient)
 
-	// Init Controller
-	router.Post("/notifications/push", func(c *fiber.Ctx) error {
-		ctx, span := telemetry.StartSpan(c.UserContext(), "controller:PushNotification")
-		defer span.End()
+	app := newHTTPApp(notificationHandler, drainer.FiberMiddleware())
+	app.Get(HealthzPath, adaptor.HTTPHandler(probes.LivenessHandler()))
+	app.Get(ReadyzPath, adaptor.HTTPHandler(probes.ReadinessHandler()))
+	return app
+}
 
-		var req notification.PushNotificationRequest
-		if err := c.BodyParser(&req); err != nil {
-			zap.L().Error("Cannot unmarshal body", zap.ByteString("body", c.BodyRaw()), zap.Error(err))
-			return err
-		}
+// newAdminServer serves the telemetry admin API and status, listening on a
+// loopback address out of reach of the notification clients.
+func newAdminServer() *fiber.App {
+	admin := fiber.New(fiber.Config{DisableStartupMessage: true})
+	admin.All(AdminPath, adaptor.HTTPHandler(telemetry.AdminHandler()))
+	admin.Get(StatusPath, adaptor.HTTPHandler(telemetry.StatusHandler()))
+	return admin
+}
 
-		if err := notificationHandler.SendPushNotification(ctx, req); err != nil {
-			zap.L().Error("Unable to send notification", zap.Error(err))
-			return err
+// newHTTPApp returns the client's Fiber app. The middleware run before the
+// telemetry middleware.
+func newHTTPApp(notificationHandler notification.Handler, middleware ...fiber.Handler) *fiber.App {
+	// Init HTTP Server
+	mux := fiber.New()
+	for _, handler := range middleware {
+		mux.Use(handler)
+	}
+	skipOperationalPaths := func(c *fiber.Ctx) bool {
+		switch c.Path() {
+		case MetricsPath, HealthzPath, ReadyzPath:
+			return true
 		}
+		return false
+	}
+	mux.Use(otelfiber.Middleware(otelfiber.WithNext(skipOperationalPaths), otelfiber.WithoutMetrics(true)))
+	mux.Use(telemetry.FiberMetrics(ServiceName, skipOperationalPaths))
+	if handler := telemetry.PrometheusHandler(); handler != nil {
+		mux.Get(MetricsPath, adaptor.HTTPHandler(handler))
+	}
+	router := mux.Group("/client")
+
+	// Init Controller
+	router.Post("/notifications/push", func(c *fiber.Ctx) error {
+		return telemetry.Do(c.UserContext(), "controller:PushNotification", func(ctx context.Context) error {
+			var req notification.PushNotificationRequest
+			if err := c.BodyParser(&req); err != nil {
+				telemetry.Logger(ctx).Error("Cannot unmarshal body", zap.Int("body.size", len(c.BodyRaw())), zap.Error(err))
+				return err
+			}
+			ctx = withRequestBaggage(ctx, c, req.UserId, "push")
 
-		return nil
+			if err := notificationHandler.SendPushNotification(ctx, req); err != nil {
+				telemetry.Logger(ctx).Error("Unable to send notification", zap.Error(err))
+				return err
+			}
+
+			return nil
+		})
 	})
 
 	router.Post("/notifications/email", func(c *fiber.Ctx) error {
-		ctx, span := telemetry.StartSpan(c.UserContext(), "controller:EmailNotification")
-		defer span.End()
-
-		var req notification.EmailNotificationRequest
-		if err := c.BodyParser(&req); err != nil {
-			zap.L().Error("Cannot unmarshal body", zap.ByteString("body", c.BodyRaw()), zap.Error(err))
-			return err
-		}
+		return telemetry.Do(c.UserContext(), "controller:EmailNotification", func(ctx context.Context) error {
+			var req notification.EmailNotificationRequest
+			if err := c.BodyParser(&req); err != nil {
+				telemetry.Logger(ctx).Error("Cannot unmarshal body", zap.Int("body.size", len(c.BodyRaw())), zap.Error(err))
+				return err
+			}
+			ctx = withRequestBaggage(ctx, c, req.UserId, "email")
 
-		respBody, err := notificationHandler.SendEmailNotification(ctx, req)
-		if err != nil {
-			zap.L().Error("Unable to send notification", zap.Error(err))
-			return err
-		}
+			respBody, err := notificationHandler.SendEmailNotification(ctx, req)
+			if err != nil {
+				telemetry.Logger(ctx).Error("Unable to send notification", zap.Error(err))
+				return err
+			}
 
-		var resp map[string]interface{}
-		if err := json.Unmarshal(respBody, &resp); err != nil {
-			return err
-		}
+			var resp map[string]interface{}
+			if err := json.Unmarshal(respBody, &resp); err != nil {
+				return err
+			}
 
-		return c.JSON(resp, "application/json")
+			return c.JSON(resp, "application/json")
+		})
 	})
 
-	// Run http server
-	go func() {
-		if err := mux.Listen(":8081"); err != nil {
-			zap.L().Fatal("Cannot run http server", zap.Error(err))
-		}
-	}()
-
 	return mux
 }
+
+// withRequestBaggage adds the request scoped keys to the baggage propagated
+// with every outgoing call.
+func withRequestBaggage(ctx context.Context, c *fiber.Ctx, userID int64, notificationType string) context.Context {
+	origin := c.Get(OriginHeader)
+	if origin == "" {
+		origin = ServiceName
+	}
+
+	ctx, err := telemetry.ContextWithBaggage(ctx,
+		BaggageTenantID, c.Get(TenantIDHeader),
+		BaggageUserID, strconv.FormatInt(userID, 10),
+		BaggageNotificationType, notificationType,
+		BaggageOrigin, origin,
+	)
+	if err != nil {
+		telemetry.Logger(ctx).Warn("Cannot set request baggage", zap.Error(err))
+	}
+	return ctx
+}
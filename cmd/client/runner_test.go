@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/wahyurudiyan/go-otel-context-propagation/pkg/telemetry/telemetrytest"
+)
+
+func TestStatusServedOnAdminServerOnly(t *testing.T) {
+	telemetrytest.Install(t, "notification-client-test")
+
+	for _, tt := range []struct {
+		name string
+		app  *fiber.App
+		want int
+	}{
+		{name: "public", app: newTestApp(t, "http://127.0.0.1:1", "127.0.0.1:1"), want: fiber.StatusNotFound},
+		{name: "admin", app: newAdminServer(), want: fiber.StatusOK},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.app.Test(httptest.NewRequest("GET", StatusPath, nil), -1)
+			if err != nil {
+				t.Fatalf("%s: %v", StatusPath, err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("%s: status %d, want %d", StatusPath, resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
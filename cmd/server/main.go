@@ -2,42 +2,146 @@ package main
 
 import (
 	"context"
-	"errors"
+	"os"
+	"time"
 
+	"github.com/wahyurudiyan/go-otel-context-propagation/cmd/server/notification"
 	"github.com/wahyurudiyan/go-otel-context-propagation/pkg/graceful"
 	"github.com/wahyurudiyan/go-otel-context-propagation/pkg/telemetry"
 	"go.uber.org/zap"
 )
 
 const (
-	HTTPServerAddr = "0.0.0.0:8080"
-	GRPCServerAddr = "0.0.0.0:9090"
+	HTTPServerAddr    = "0.0.0.0:8080"
+	GRPCServerAddr    = "0.0.0.0:9090"
+	MetricsPath       = "/metrics"
+	StatusPath        = "/debug/telemetry"
+	HealthzPath       = "/healthz"
+	ReadyzPath        = "/readyz"
+	AdminAddr         = "127.0.0.1:8090"
+	AdminPath         = "/admin/telemetry"
+	RuntimeConfigFile = "telemetry-runtime.json"
+	ServiceName       = "notification-server"
+
+	TelemetryComponent = "telemetry"
+	ReadinessComponent = "readiness"
+	ShutdownTimeout    = 30 * time.Second
+	ServerStopTimeout  = 10 * time.Second
+
+	// Servers wait DrainGracePeriod for their requests to finish, then are
+	// closed forcibly within the rest of ServerStopTimeout.
+	DrainGracePeriod = 8 * time.Second
+
+	// The readiness fails when the span queue is fuller than SpanQueueLimit
+	// of its capacity; the checks are published to gRPC health clients
+	// every HealthCheckInterval.
+	SpanQueueLimit      = 0.9
+	HealthCheckInterval = 5 * time.Second
+
+	// The readiness also fails once an exporter has been failing for the
+	// duration set in ExporterFailureLimitEnv, e.g. "5m". Exporter failures
+	// leave the process ready when it is unset.
+	ExporterFailureLimitEnv = "READINESS_EXPORTER_FAILURE_LIMIT"
+
+	// The servers keep accepting requests for ReadinessDelay after the
+	// readiness turns not ready, for load balancers to notice.
+	ReadinessDelay = 2 * time.Second
 )
 
+// Process level metrics reported next to the notification metrics. Host
+// wide metrics are left to the collector.
+var processMetricGroups = []telemetry.MetricGroup{
+	telemetry.MetricGroupRuntime,
+	telemetry.MetricGroupGC,
+	telemetry.MetricGroupProcess,
+}
+
+// Baggage members set by the client and recorded on server telemetry.
+var promotedBaggage = telemetry.BaggageConfig{
+	AllowedKeys: []string{"tenant_id", "user_id", "notification_type", "origin"},
+	Redact: map[string]telemetry.RedactFunc{
+		"user_id": telemetry.HashValue,
+	},
+}
+
+// Keys of the notification data map recorded on the handler spans. Other
+// keys sent by clients are never recorded.
+var notificationData = notification.DataConfig{
+	AllowedKeys: []string{"campaign"},
+}
+
+// logLevel is the level of the global logger, changed through the admin API
+// or by reloading RuntimeConfigFile on SIGHUP.
+var logLevel zap.AtomicLevel
+
 func init() {
 	zapConfig := zap.NewDevelopmentConfig()
+	logLevel = zapConfig.Level
 	zap.ReplaceGlobals(zap.Must(zapConfig.Build()))
 }
 
 func main() {
 	zap.L().Info("Starting GRPC server")
 
-	// initialize graceful shutdown
-	ctx := context.Background()
+	lifecycle := graceful.New(graceful.WithShutdownTimeout(ShutdownTimeout))
+	lifecycle.Register(telemetryComponent())
+	lifecycle.Register(components()...)
 
-	shutdown, err := telemetry.SetupOTelSDK(ctx, "server.grpc")
-	if err != nil {
-		zap.L().Fatal("unable to setup OTelSDK", zap.Error(err))
+	if err := lifecycle.Run(context.Background()); err != nil {
+		zap.L().Fatal("Server cannot shutting down gracefully!", zap.Error(err))
 	}
+}
 
-	defer func() {
-		err = errors.Join(err, shutdown(context.Background()))
-		if err != nil {
-			zap.L().Error("Open Telemetry shutdown failed!", zap.Error(err))
-		}
-	}()
+// telemetryComponent sets up the OpenTelemetry SDK before the servers and
+// flushes it once they are stopped.
+func telemetryComponent() graceful.Component {
+	var (
+		shutdown      func(context.Context) error
+		restoreLogger func()
+		stopReload    context.CancelFunc
+	)
 
-	if err := graceful.Runner(ctx, wrapServer); err != nil {
-		zap.L().Fatal("Server cannot shutting down gracefully!", zap.Error(err))
+	return graceful.Component{
+		Name:      TelemetryComponent,
+		Telemetry: true,
+		Start: func(ctx context.Context) (err error) {
+			shutdown, err = telemetry.SetupOTelSDK(ctx, ServiceName,
+				telemetry.WithBaggagePromotion(promotedBaggage),
+				telemetry.WithRedactionRules(telemetry.DefaultRedactionRules...),
+				telemetry.WithMetricGroups(processMetricGroups...),
+				telemetry.WithLogLevel(logLevel),
+			)
+			if err != nil {
+				return err
+			}
+
+			// forward zap logs to the OpenTelemetry logs pipeline
+			restoreLogger = zap.ReplaceGlobals(zap.L().WithOptions(telemetry.ZapOption(ServiceName)))
+
+			var reloadCtx context.Context
+			reloadCtx, stopReload = context.WithCancel(context.Background())
+			telemetry.ReloadOnSIGHUP(reloadCtx, RuntimeConfigFile)
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			stopReload()
+			defer restoreLogger()
+			return shutdown(ctx)
+		},
+	}
+}
+
+// exporterFailureLimit returns the duration set in ExporterFailureLimitEnv,
+// or 0 when it is unset or invalid.
+func exporterFailureLimit() time.Duration {
+	value := os.Getenv(ExporterFailureLimitEnv)
+	if value == "" {
+		return 0
+	}
+	limit, err := time.ParseDuration(value)
+	if err != nil {
+		zap.L().Warn("Ignoring the exporter failure limit", zap.String("env", ExporterFailureLimitEnv), zap.Error(err))
+		return 0
 	}
+	return limit
 }
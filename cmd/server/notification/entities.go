@@ -1,5 +1,10 @@
 package notification
 
+import (
+	"github.com/wahyurudiyan/go-otel-context-propagation/pkg/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
 type PushNotificationRequest struct {
 	UserId   int64             `json:"user_id,omitempty"`
 	DeviceId string            `json:"device_id,omitempty"`
@@ -15,3 +20,30 @@ type EmailNotificationRequest struct {
 	Body    string            `json:"body,omitempty"`
 	Data    map[string]string `json:"data,omitempty"`
 }
+
+// DataConfig selects the keys of the notification Data map recorded on the
+// handler spans, named "notification.data.<key>". The keys and values come
+// from the client, so the zero value records none of them.
+type DataConfig struct {
+	// AllowedKeys lists the keys that are recorded. Other keys are never
+	// recorded.
+	AllowedKeys []string
+	// Redact maps an allowed key to the function applied to its value.
+	Redact map[string]telemetry.RedactFunc
+}
+
+// attributes describes the allowed keys of data as span attributes.
+func (c DataConfig) attributes(data map[string]string) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	for _, key := range c.AllowedKeys {
+		value, ok := data[key]
+		if !ok {
+			continue
+		}
+		if redact := c.Redact[key]; redact != nil {
+			value = redact(value)
+		}
+		attrs = append(attrs, attribute.String("notification.data."+key, value))
+	}
+	return attrs
+}
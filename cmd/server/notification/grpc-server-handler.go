@@ -4,27 +4,36 @@ import (
 	"context"
 
 	"github.com/wahyurudiyan/go-otel-context-propagation/contract/notificationpb"
+	"github.com/wahyurudiyan/go-otel-context-propagation/pkg/notificationmetrics"
 	"github.com/wahyurudiyan/go-otel-context-propagation/pkg/telemetry"
-	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
 )
 
 type grpcHandler struct {
 	notificationpb.UnimplementedNotificationServiceServer
+	metrics *notificationmetrics.Metrics
+	data    DataConfig
 }
 
-func NewNotificationGRPCHandler() notificationpb.NotificationServiceServer {
-	return &grpcHandler{}
+// NewNotificationGRPCHandler returns the gRPC handler, recording the Data
+// keys selected by data on its spans.
+func NewNotificationGRPCHandler(data DataConfig) notificationpb.NotificationServiceServer {
+	return &grpcHandler{
+		metrics: newNotificationMetrics(),
+		data:    data,
+	}
 }
 
-func (h *grpcHandler) SendPushNotification(ctx context.Context, req *notificationpb.PushNotificationRequest) (*notificationpb.PushNotificationResponse, error) {
-	_, span := telemetry.StartSpan(ctx, "grpcHandler:SendPushNotification")
+func (h *grpcHandler) SendPushNotification(ctx context.Context, req *notificationpb.PushNotificationRequest) (_ *notificationpb.PushNotificationResponse, err error) {
+	ctx, span := telemetry.StartSpan(ctx, "grpcHandler:SendPushNotification")
 	defer span.End()
 
-	spanCtx := span.SpanContext()
-	zap.L().Info("grpc.SendPushNotification: span info",
-		zap.String("span.id", spanCtx.SpanID().String()),
-		zap.String("trace.id", spanCtx.TraceID().String()),
-	)
+	done := h.metrics.Start(ctx, notificationmetrics.ChannelPush, notificationmetrics.TransportGRPC, proto.Size(req))
+	defer func() { done(err) }()
+
+	span.SetAttributes(h.data.attributes(req.GetData())...)
+
+	telemetry.Logger(ctx).Info("grpc.SendPushNotification: span info")
 
 	return &notificationpb.PushNotificationResponse{
 		Success: true,
@@ -2,48 +2,55 @@ package notification
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/wahyurudiyan/go-otel-context-propagation/pkg/notificationmetrics"
 	"github.com/wahyurudiyan/go-otel-context-propagation/pkg/telemetry"
 	"go.uber.org/zap"
 )
 
-type httpHandler struct{}
+type httpHandler struct {
+	metrics *notificationmetrics.Metrics
+	data    DataConfig
+}
 
 type HTTPHandler interface {
 	SendEmailNotification() fiber.Handler
 }
 
-func NewNotificationHTTPHandler() HTTPHandler {
-	return &httpHandler{}
+// NewNotificationHTTPHandler returns the HTTP handler, recording the Data
+// keys selected by data on its spans.
+func NewNotificationHTTPHandler(data DataConfig) HTTPHandler {
+	return &httpHandler{
+		metrics: newNotificationMetrics(),
+		data:    data,
+	}
 }
 
 func (h *httpHandler) SendEmailNotification() fiber.Handler {
-	return func(fiberCtx *fiber.Ctx) error {
-		_, span := telemetry.StartSpan(fiberCtx.UserContext(), "httpHandler:SendEmailNotification")
+	return func(fiberCtx *fiber.Ctx) (err error) {
+		ctx, span := telemetry.StartSpan(fiberCtx.UserContext(), "httpHandler:SendEmailNotification")
 		defer span.End()
 		spanCtx := span.SpanContext()
+		logger := telemetry.Logger(ctx)
 
-		zap.L().Debug("body request", zap.ByteString("payload", fiberCtx.Body()))
+		done := h.metrics.Start(ctx, notificationmetrics.ChannelEmail, notificationmetrics.TransportHTTP, len(fiberCtx.Body()))
+		defer func() { done(err) }()
+
+		logger.Debug("body request", zap.Int("payload.size", len(fiberCtx.Body())))
 
 		var req EmailNotificationRequest
 		if err := fiberCtx.BodyParser(&req); err != nil {
-			zap.L().Error("http.SendEmailNotification: error occur",
-				zap.Error(err),
-				zap.String("span.id", spanCtx.SpanID().String()),
-				zap.String("trace.id", spanCtx.TraceID().String()),
-			)
+			telemetry.RecordError(span, err)
+			logger.Error("http.SendEmailNotification: error occur", zap.Error(err))
 			return err
 		}
 
-		zap.L().Info("http.SendEmailNotification: span info",
-			zap.String("span.id", spanCtx.SpanID().String()),
-			zap.String("trace.id", spanCtx.TraceID().String()),
-			zap.String("email.to", req.Email),
-		)
+		span.SetAttributes(h.data.attributes(req.Data)...)
+
+		logger.Info("http.SendEmailNotification: span info")
 
 		return fiberCtx.JSON(map[string]interface{}{
 			"success":  true,
 			"message":  "email sent",
-			"payload":  req,
 			"trace_id": spanCtx.TraceID().String(),
 		})
 	}
@@ -0,0 +1,9 @@
+package notification
+
+import "github.com/wahyurudiyan/go-otel-context-propagation/pkg/notificationmetrics"
+
+const meterName = "github.com/wahyurudiyan/go-otel-context-propagation/cmd/server/notification"
+
+func newNotificationMetrics() *notificationmetrics.Metrics {
+	return notificationmetrics.New(meterName)
+}
@@ -3,100 +3,168 @@ package main
 import (
 	"context"
 	"net"
-	"sync"
 
 	"github.com/gofiber/contrib/otelfiber/v2"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/wahyurudiyan/go-otel-context-propagation/cmd/server/notification"
 	"github.com/wahyurudiyan/go-otel-context-propagation/contract/notificationpb"
 	"github.com/wahyurudiyan/go-otel-context-propagation/pkg/graceful"
+	"github.com/wahyurudiyan/go-otel-context-propagation/pkg/health"
+	"github.com/wahyurudiyan/go-otel-context-propagation/pkg/telemetry"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
-	"go.opentelemetry.io/otel/attribute"
-	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 )
 
-func wrapServer(ctx context.Context) graceful.ShutdownCallback {
-	httpServer := startHTTPServer()
-	grpcServer := startGRPCServer()
-	return func(ctx context.Context) error {
-		var wg sync.WaitGroup
-		chanErr := make(chan error)
-
-		go func(ctx context.Context) {
-			wg.Add(1)
-			defer wg.Done()
-
-			if err := httpServer.ShutdownWithContext(ctx); err != nil {
-				chanErr <- err
-			}
-		}(context.Background())
-
-		go func() {
-			wg.Add(1)
-			defer wg.Done()
-
-			grpcServer.GracefulStop()
-		}()
-		wg.Wait()
+// components returns the servers, each started after and stopped before the
+// telemetry they report to. Their listeners are bound on start, so a port in
+// use fails the start, and serve errors shut the process down. The
+// readiness turns ready once all servers run and is the first to stop.
+func components() []graceful.Component {
+	probes := health.New(health.WithShutdownDelay(ReadinessDelay))
+	probes.AddCheck("span-queue", telemetry.SpanQueueCheck(SpanQueueLimit))
+	if limit := exporterFailureLimit(); limit > 0 {
+		probes.AddCheck("telemetry-exporters", telemetry.CheckExporters(limit))
+	}
+	httpDrainer := graceful.NewDrainer("http-server", DrainGracePeriod)
+	grpcDrainer := graceful.NewDrainer("grpc-server", DrainGracePeriod)
+
+	var (
+		httpServer    *fiber.App
+		httpListener  net.Listener
+		grpcServer    *grpc.Server
+		grpcListener  net.Listener
+		adminServer   *fiber.App
+		adminListener net.Listener
+	)
 
-		select {
-		case err := <-chanErr:
-			return err
-		case <-ctx.Done():
-			return nil
-		}
+	return []graceful.Component{
+		{
+			Name:      "http-server",
+			DependsOn: []string{TelemetryComponent},
+			Start: func(context.Context) (err error) {
+				httpServer = newHTTPServer(probes, httpDrainer)
+				if httpListener, err = net.Listen("tcp", HTTPServerAddr); err != nil {
+					return err
+				}
+				httpListener = httpDrainer.Listener(httpListener)
+				zap.L().Info("HTTP server is running!", zap.String("http.address", HTTPServerAddr))
+				return nil
+			},
+			Run: func() error {
+				return httpServer.Listener(httpListener)
+			},
+			Stop: func(ctx context.Context) error {
+				return httpDrainer.Drain(ctx, httpServer.ShutdownWithContext, nil)
+			},
+			StopTimeout: ServerStopTimeout,
+			InFlight:    httpDrainer.InFlight,
+		},
+		{
+			Name:      "grpc-server",
+			DependsOn: []string{TelemetryComponent},
+			Start: func(context.Context) (err error) {
+				grpcServer = newGRPCServer(probes, grpcDrainer)
+				if grpcListener, err = net.Listen("tcp", GRPCServerAddr); err != nil {
+					return err
+				}
+				zap.L().Info("GRPC server is running!", zap.String("grpc.address", GRPCServerAddr))
+				return nil
+			},
+			Run: func() error {
+				return grpcServer.Serve(grpcListener)
+			},
+			Stop: func(ctx context.Context) error {
+				return grpcDrainer.Drain(ctx, func(context.Context) error {
+					grpcServer.GracefulStop()
+					return nil
+				}, grpcServer.Stop)
+			},
+			StopTimeout: ServerStopTimeout,
+			InFlight:    grpcDrainer.InFlight,
+		},
+		{
+			Name:      "admin-server",
+			DependsOn: []string{TelemetryComponent},
+			Start: func(context.Context) (err error) {
+				adminServer = newAdminServer()
+				if adminListener, err = net.Listen("tcp", AdminAddr); err != nil {
+					return err
+				}
+				zap.L().Info("Admin server is running!", zap.String("admin.address", AdminAddr))
+				return nil
+			},
+			Run: func() error {
+				return adminServer.Listener(adminListener)
+			},
+			Stop: func(ctx context.Context) error {
+				return adminServer.ShutdownWithContext(ctx)
+			},
+			StopTimeout: ServerStopTimeout,
+		},
+		{
+			Name:      ReadinessComponent,
+			DependsOn: []string{"http-server", "grpc-server"},
+			Start: func(context.Context) error {
+				probes.Start(HealthCheckInterval)
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				return probes.Shutdown(ctx)
+			},
+		},
 	}
 }
 
-func startHTTPServer() *fiber.App {
+func newHTTPServer(probes *health.Health, drainer *graceful.Drainer) *fiber.App {
 	mux := fiber.New()
-	mux.Use(otelfiber.Middleware(otelfiber.WithCustomAttributes(
-		func(ctx *fiber.Ctx) []attribute.KeyValue {
-			return []attribute.KeyValue{
-				semconv.ServiceName("notification:server"),
-			}
-		},
-	)))
+	mux.Use(drainer.FiberMiddleware())
+	skipOperationalPaths := func(c *fiber.Ctx) bool {
+		switch c.Path() {
+		case MetricsPath, HealthzPath, ReadyzPath:
+			return true
+		}
+		return false
+	}
+	mux.Use(otelfiber.Middleware(otelfiber.WithNext(skipOperationalPaths), otelfiber.WithoutMetrics(true)))
+	mux.Use(telemetry.FiberMetrics(ServiceName, skipOperationalPaths))
+	if handler := telemetry.PrometheusHandler(); handler != nil {
+		mux.Get(MetricsPath, adaptor.HTTPHandler(handler))
+	}
+	mux.Get(HealthzPath, adaptor.HTTPHandler(probes.LivenessHandler()))
+	mux.Get(ReadyzPath, adaptor.HTTPHandler(probes.ReadinessHandler()))
 	router := mux.Group("/server")
 
-	handler := notification.NewNotificationHTTPHandler()
+	handler := notification.NewNotificationHTTPHandler(notificationData)
 	router.Post("/notifications/email", handler.SendEmailNotification())
 
-	zap.L().Info("HTTP server is running!", zap.String("http.address", HTTPServerAddr))
-	go func() {
-		if err := mux.Listen(HTTPServerAddr); err != nil {
-			zap.L().Fatal("Cannot start HTTP server!", zap.Error(err))
-		}
-	}()
-
 	return mux
 }
 
-func startGRPCServer() *grpc.Server {
-	// initialize tcp network listener
-	lst, err := net.Listen("tcp", GRPCServerAddr)
-	if err != nil {
-		zap.L().Fatal("Failed to listen tcp", zap.Error(err))
-	}
+// newAdminServer serves the telemetry admin API and status, listening on a
+// loopback address out of reach of the notification clients.
+func newAdminServer() *fiber.App {
+	admin := fiber.New(fiber.Config{DisableStartupMessage: true})
+	admin.All(AdminPath, adaptor.HTTPHandler(telemetry.AdminHandler()))
+	admin.Get(StatusPath, adaptor.HTTPHandler(telemetry.StatusHandler()))
+	return admin
+}
 
+func newGRPCServer(probes *health.Health, drainer *graceful.Drainer) *grpc.Server {
 	// initialize grpc server
 	grpcServer := grpc.NewServer(
-		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		// Health probes are not traced, like the probe routes of the HTTP server.
+		grpc.StatsHandler(otelgrpc.NewServerHandler(otelgrpc.WithFilter(filters.Not(filters.HealthCheck())))),
+		grpc.ChainUnaryInterceptor(drainer.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(drainer.StreamServerInterceptor()),
 	)
 
 	// initialize handler and register into server
-	notificationGRPCHandler := notification.NewNotificationGRPCHandler()
+	notificationGRPCHandler := notification.NewNotificationGRPCHandler(notificationData)
 	notificationpb.RegisterNotificationServiceServer(grpcServer, notificationGRPCHandler)
-
-	// run grpc server
-	zap.L().Info("GRPC server is running!", zap.String("grpc.address", GRPCServerAddr))
-	go func() {
-		if err := grpcServer.Serve(lst); err != nil {
-			zap.L().Fatal("Cannot start GRPC server", zap.Error(err))
-		}
-	}()
+	probes.RegisterGRPC(grpcServer)
 
 	return grpcServer
 }
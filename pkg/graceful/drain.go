@@ -0,0 +1,197 @@
+package graceful
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+const instrumentationName = "github.com/wahyurudiyan/go-otel-context-propagation/pkg/graceful"
+
+// ErrDrainTimeout is returned by Drainer.Drain when requests were still in
+// flight at the end of the grace period and were cut off.
+var ErrDrainTimeout = errors.New("graceful: drain grace period exceeded")
+
+// Drainer tracks the requests in flight on a server and stops the server
+// in two phases: it waits up to a grace period for them to finish, then
+// closes the server forcibly and reports the requests cut off.
+type Drainer struct {
+	name  string
+	grace time.Duration
+
+	inFlight atomic.Int64
+	cutOff   metric.Int64Counter
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+// NewDrainer returns a Drainer for the server of the component name,
+// waiting up to grace for its requests. The grace period should be shorter
+// than the StopTimeout of the component to leave time for the forced
+// close.
+func NewDrainer(name string, grace time.Duration) *Drainer {
+	cutOff, err := otel.Meter(instrumentationName).Int64Counter("graceful.drain.requests.cut_off",
+		metric.WithDescription("Requests still in flight when the server was closed forcibly at the end of the drain."),
+		metric.WithUnit("{request}"))
+	if err != nil {
+		otel.Handle(err)
+	}
+	return &Drainer{name: name, grace: grace, cutOff: cutOff, conns: make(map[net.Conn]struct{})}
+}
+
+// InFlight returns the number of requests being served.
+func (d *Drainer) InFlight() int64 {
+	return d.inFlight.Load()
+}
+
+// track counts a request until the returned function is called.
+func (d *Drainer) track() (done func()) {
+	d.inFlight.Add(1)
+	return func() { d.inFlight.Add(-1) }
+}
+
+// FiberMiddleware returns a middleware counting the requests of a Fiber
+// app. It must be registered first so it covers the other handlers.
+func (d *Drainer) FiberMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		defer d.track()()
+		return c.Next()
+	}
+}
+
+// UnaryServerInterceptor counts the unary RPCs of a gRPC server.
+func (d *Drainer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		defer d.track()()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor counts the streaming RPCs of a gRPC server.
+func (d *Drainer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		defer d.track()()
+		return handler(srv, ss)
+	}
+}
+
+// Listener returns ln recording the connections it accepts, which Drain
+// closes when the grace period is over. Servers without a forced stop of
+// their own, such as Fiber, must serve through it.
+func (d *Drainer) Listener(ln net.Listener) net.Listener {
+	return &drainListener{Listener: ln, drainer: d}
+}
+
+// Drain calls stop and waits up to the grace period, or until ctx is done,
+// for it to return and for the requests in flight to finish. The ctx given
+// to stop is done at the end of the grace period. Then force is called,
+// when set, and the connections accepted through Listener are closed. The
+// requests cut off are logged, counted in graceful.drain.requests.cut_off
+// and reported as ErrDrainTimeout, or as the cause of ctx when it ended
+// first. Drain waits for stop to return within ctx.
+func (d *Drainer) Drain(ctx context.Context, stop func(context.Context) error, force func()) error {
+	begin := time.Now()
+	graceCtx, cancel := context.WithTimeout(ctx, d.grace)
+	defer cancel()
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- stop(graceCtx)
+	}()
+
+	zap.L().Info("Draining requests", zap.String("component", d.name), zap.Int64("requests.in_flight", d.InFlight()))
+	returned := false
+	select {
+	case err := <-stopped:
+		// A server giving up on its requests at the end of the grace
+		// period, like Fiber, returns the context error.
+		if err == nil || graceCtx.Err() == nil {
+			zap.L().Info("Requests drained", zap.String("component", d.name), zap.Duration("duration", time.Since(begin)))
+			return err
+		}
+		returned = true
+	case <-graceCtx.Done():
+	}
+
+	cutOff := d.InFlight()
+	if force != nil {
+		force()
+	}
+	d.closeConns()
+
+	zap.L().Warn("Requests cut off", zap.String("component", d.name),
+		zap.Int64("requests.cut_off", cutOff), zap.Duration("grace_period", d.grace))
+	if d.cutOff != nil {
+		d.cutOff.Add(context.WithoutCancel(ctx), cutOff, metric.WithAttributes(attribute.String("graceful.component", d.name)))
+	}
+
+	if !returned {
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+		}
+	}
+	if cause := context.Cause(ctx); cause != nil {
+		// The shutdown deadline passed or the shutdown was forced before
+		// the end of the grace period.
+		return fmt.Errorf("graceful: %d requests cut off: %w", cutOff, cause)
+	}
+	return fmt.Errorf("%w: %d requests cut off after %s", ErrDrainTimeout, cutOff, d.grace)
+}
+
+func (d *Drainer) closeConns() {
+	d.mu.Lock()
+	conns := d.conns
+	d.conns = make(map[net.Conn]struct{})
+	d.mu.Unlock()
+
+	for conn := range conns {
+		_ = conn.Close()
+	}
+}
+
+// drainListener records the connections it accepts until they are closed.
+type drainListener struct {
+	net.Listener
+	drainer *Drainer
+}
+
+func (l *drainListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &drainConn{Conn: conn, drainer: l.drainer}
+	l.drainer.mu.Lock()
+	l.drainer.conns[c] = struct{}{}
+	l.drainer.mu.Unlock()
+	return c, nil
+}
+
+type drainConn struct {
+	net.Conn
+	drainer *Drainer
+	once    sync.Once
+}
+
+func (c *drainConn) Close() error {
+	c.once.Do(func() {
+		c.drainer.mu.Lock()
+		delete(c.drainer.conns, c)
+		c.drainer.mu.Unlock()
+	})
+	return c.Conn.Close()
+}
@@ -0,0 +1,127 @@
+package graceful
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestDrainerDrained(t *testing.T) {
+	d := NewDrainer("http", time.Second)
+	app, url := startDrainedApp(t, d)
+
+	resp, err := http.Get(url + "/fast")
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if err := d.Drain(context.Background(), app.ShutdownWithContext, nil); err != nil {
+		t.Errorf("drain = %v, want nil", err)
+	}
+}
+
+func TestDrainerForceClose(t *testing.T) {
+	tests := []struct {
+		name  string
+		grace time.Duration
+		// cancel, when set, ends the shutdown before the grace period.
+		cancel error
+		want   error
+	}{
+		{name: "grace period over", grace: 50 * time.Millisecond, want: ErrDrainTimeout},
+		{name: "shutdown forced", grace: time.Hour, cancel: ErrForcedShutdown, want: ErrForcedShutdown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDrainer("http", tt.grace)
+			_, url := startDrainedApp(t, d)
+
+			failed := make(chan error, 1)
+			go func() {
+				resp, err := http.Get(url + "/slow")
+				if err == nil {
+					_ = resp.Body.Close()
+				}
+				failed <- err
+			}()
+			waitInFlight(t, d, 1)
+
+			ctx, cancel := context.WithCancelCause(context.Background())
+			defer cancel(nil)
+			if tt.cancel != nil {
+				cancel(tt.cancel)
+			}
+
+			var forced bool
+			begin := time.Now()
+			err := d.Drain(ctx, func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}, func() { forced = true })
+
+			if !errors.Is(err, tt.want) || !strings.Contains(err.Error(), "1 requests cut off") {
+				t.Errorf("drain = %v, want %v with 1 request cut off", err, tt.want)
+			}
+			if elapsed := time.Since(begin); tt.cancel == nil && elapsed < tt.grace {
+				t.Errorf("drained in %s, before the %s grace period", elapsed, tt.grace)
+			}
+			if !forced {
+				t.Errorf("force was not called")
+			}
+			select {
+			case err := <-failed:
+				if err == nil {
+					t.Errorf("request cut off succeeded")
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("request still open after the drain")
+			}
+		})
+	}
+}
+
+// startDrainedApp serves a Fiber app counting its requests with d until
+// the test ends. The handler of /slow blocks until the test ends.
+func startDrainedApp(t *testing.T, d *Drainer) (*fiber.App, string) {
+	t.Helper()
+
+	release := make(chan struct{})
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(d.FiberMiddleware())
+	app.Get("/fast", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	app.Get("/slow", func(c *fiber.Ctx) error {
+		<-release
+		return c.SendString("ok")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() { _ = app.Listener(d.Listener(ln)) }()
+	t.Cleanup(func() {
+		close(release)
+		_ = app.Shutdown()
+	})
+	return app, "http://" + ln.Addr().String()
+}
+
+func waitInFlight(t *testing.T, d *Drainer, want int64) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for d.InFlight() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d requests in flight, want %d", d.InFlight(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
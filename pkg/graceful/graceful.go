@@ -2,28 +2,470 @@ package graceful
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
 	"os/signal"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
-type ShutdownCallback func(context.Context) error
+const (
+	defaultShutdownTimeout  = 30 * time.Second
+	defaultProgressInterval = 2 * time.Second
+	defaultFlushTimeout     = 5 * time.Second
+)
+
+// ErrForcedShutdown is the cause of the context given to the Stop functions
+// when a signal is received again during the shutdown: the components not
+// stopped yet are given up on.
+var ErrForcedShutdown = errors.New("graceful: shutdown forced by a repeated signal")
+
+// Component is a part of the process started and stopped by a Manager,
+// such as a server, a client connection, a worker or the telemetry pipeline.
+type Component struct {
+	// Name identifies the component in logs, DependsOn and the Report.
+	Name string
+	// DependsOn names the components started before and stopped after this
+	// one.
+	DependsOn []string
+
+	// Start returns once the component is running; work lasting for the
+	// life of the component runs in goroutines. Its ctx is done when Start
+	// returns or after StartTimeout.
+	Start func(ctx context.Context) error
+	// Run, when set, is called in a goroutine once the component started and
+	// blocks while the component works, e.g. serving a listener. An error
+	// returned before the shutdown began shuts down all components and is
+	// returned by Manager.Run, like a failing function of an errgroup.
+	Run func() error
+	// Stop releases the component. Its ctx is done after StopTimeout or at
+	// the shutdown deadline, whichever comes first. A Stop still running
+	// then is abandoned and the next component is stopped.
+	Stop func(ctx context.Context) error
+
+	// StartTimeout and StopTimeout bound Start and Stop. Zero leaves Start
+	// unbounded and Stop bounded by the shutdown deadline only.
+	StartTimeout time.Duration
+	StopTimeout  time.Duration
+
+	// InFlight, when set, returns the number of requests the component is
+	// serving, logged while the shutdown is in progress, e.g.
+	// Drainer.InFlight.
+	InFlight func() int64
+	// Telemetry marks the component exporting the telemetry of the others.
+	// The shutdown span ends before it is stopped, so the span is exported
+	// with the rest. Once the shutdown is forced or past its deadline, its
+	// Stop still gets up to the flush timeout.
+	Telemetry bool
+}
+
+// Option configures a Manager.
+type Option func(*Manager)
+
+// WithShutdownTimeout sets the deadline for stopping all components,
+// 30 seconds by default.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(m *Manager) {
+		m.shutdownTimeout = timeout
+	}
+}
+
+// WithSignals replaces the signals starting the shutdown in Run, SIGINT
+// and SIGTERM by default.
+func WithSignals(signals ...os.Signal) Option {
+	return func(m *Manager) {
+		m.signals = signals
+	}
+}
+
+// WithProgressInterval sets how often the components left to stop are
+// logged during the shutdown, every 2 seconds by default.
+func WithProgressInterval(interval time.Duration) Option {
+	return func(m *Manager) {
+		m.progressInterval = interval
+	}
+}
+
+// WithFlushTimeout sets how long the Stop of a Telemetry component may run
+// once the shutdown is forced or past its deadline, 5 seconds by default.
+func WithFlushTimeout(timeout time.Duration) Option {
+	return func(m *Manager) {
+		m.flushTimeout = timeout
+	}
+}
+
+// Manager starts the registered components in dependency order and stops
+// them in reverse.
+type Manager struct {
+	shutdownTimeout  time.Duration
+	signals          []os.Signal
+	progressInterval time.Duration
+	flushTimeout     time.Duration
+
+	// failures receives the first error returned by a Run function.
+	failures chan error
+
+	mu         sync.Mutex
+	components []Component
+	started    []Component
+	stopping   bool
+}
+
+// New returns a Manager without components.
+func New(opts ...Option) *Manager {
+	m := &Manager{
+		shutdownTimeout:  defaultShutdownTimeout,
+		signals:          []os.Signal{syscall.SIGINT, syscall.SIGTERM},
+		progressInterval: defaultProgressInterval,
+		flushTimeout:     defaultFlushTimeout,
+		failures:         make(chan error, 1),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Register adds components. Components without dependencies between them
+// are started in the order they are registered.
+func (m *Manager) Register(components ...Component) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.components = append(m.components, components...)
+}
+
+// Run starts the components, waits for one of the signals, for a component
+// to fail or for ctx to be done and stops them. A signal received during
+// the shutdown forces it: the components not stopped yet are given up on
+// with ErrForcedShutdown, except for the telemetry flush. The error of the
+// failed component is returned, joined with a *ShutdownError when
+// components failed to stop.
+func (m *Manager) Run(ctx context.Context) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, m.signals...)
+	defer signal.Stop(signals)
+
+	if err := m.Start(ctx); err != nil {
+		return err
+	}
+
+	var err error
+	var reason string
+	select {
+	case sig := <-signals:
+		zap.L().Info("Shutting down", zap.Stringer("signal", sig))
+		reason = "signal " + sig.String()
+	case err = <-m.failures:
+		zap.L().Error("Shutting down after a component failed", zap.Error(err))
+		reason = "component failed"
+	case <-ctx.Done():
+		zap.L().Info("Shutting down", zap.NamedError("reason", context.Cause(ctx)))
+		reason = "context done"
+	}
+
+	stopCtx, force := context.WithCancelCause(context.WithoutCancel(ctx))
+	defer force(nil)
+	go func() {
+		select {
+		case sig := <-signals:
+			zap.L().Warn("Shutting down immediately", zap.Stringer("signal", sig))
+			force(ErrForcedShutdown)
+		case <-stopCtx.Done():
+		}
+	}()
+
+	return errors.Join(err, m.stop(stopCtx, reason).Err())
+}
+
+// Start starts the components that are not running yet, each after the
+// components it depends on. When one fails, the components started so far
+// are stopped again and the error is returned.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	m.stopping = false
+	order, err := startOrder(m.components, m.started)
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range order {
+		begin := time.Now()
+		if err := runHook(ctx, c.StartTimeout, c.Start); err != nil {
+			err = fmt.Errorf("graceful: start %s: %w", c.Name, err)
+			zap.L().Error("Component failed to start", zap.String("component", c.Name), zap.Error(err))
+			return errors.Join(err, m.Stop(context.WithoutCancel(ctx)).Err())
+		}
+		zap.L().Info("Component started", zap.String("component", c.Name), zap.Duration("duration", time.Since(begin)))
+
+		m.mu.Lock()
+		m.started = append(m.started, c)
+		m.mu.Unlock()
+
+		if c.Run != nil {
+			go m.run(c)
+		}
+	}
+	return nil
+}
 
-func Runner(ctx context.Context, callback func(ctx context.Context) ShutdownCallback) (err error) {
-	logger := zap.L()
-	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGKILL, syscall.SIGQUIT)
-	defer stop()
+// run calls the Run function of c and reports its error unless the
+// shutdown began, which is when servers return from serving.
+func (m *Manager) run(c Component) {
+	err := c.Run()
 
-	shutdown := callback(ctx)
-	<-ctx.Done()
+	m.mu.Lock()
+	stopping := m.stopping
+	m.mu.Unlock()
+	if err == nil || stopping {
+		return
+	}
+
+	err = fmt.Errorf("graceful: %s: %w", c.Name, err)
+	zap.L().Error("Component failed", zap.String("component", c.Name), zap.Error(err))
+	select {
+	case m.failures <- err:
+	default:
+		// A shutdown is already pending.
+	}
+}
+
+// Stop stops the running components in reverse start order within the
+// shutdown deadline and reports the outcome for each of them.
+func (m *Manager) Stop(ctx context.Context) *Report {
+	return m.stop(ctx, "stop")
+}
+
+// stop implements Stop, tracing the shutdown in a span with its reason and
+// a child span per component, and logging the progress until it is done.
+func (m *Manager) stop(ctx context.Context, reason string) *Report {
+	ctx, cancel := context.WithTimeout(ctx, m.shutdownTimeout)
+	defer cancel()
+
+	m.mu.Lock()
+	started := m.started
+	m.started = nil
+	m.stopping = true
+	m.mu.Unlock()
+
+	tracer := otel.Tracer(instrumentationName)
+	ctx, span := tracer.Start(ctx, "shutdown", trace.WithNewRoot(), trace.WithAttributes(
+		attribute.String("graceful.shutdown.reason", reason),
+		attribute.Int("graceful.components", len(started)),
+	))
+	spanEnded := false
+	endSpan := func(report *Report) {
+		if spanEnded {
+			return
+		}
+		spanEnded = true
+		if failed := report.Failed(); len(failed) > 0 {
+			span.SetStatus(codes.Error, "components failed to stop")
+			span.SetAttributes(attribute.StringSlice("graceful.components.failed", componentNames(failed)))
+		}
+		if errors.Is(context.Cause(ctx), ErrForcedShutdown) {
+			span.SetAttributes(attribute.Bool("graceful.shutdown.forced", true))
+		}
+		span.End()
+	}
+
+	report := &Report{}
+	begin := time.Now()
 
-	if err = shutdown(ctx); err != nil {
+	var left atomic.Int64
+	left.Store(int64(len(started)))
+	progressDone := make(chan struct{})
+	defer close(progressDone)
+	go m.logProgress(begin, started, &left, progressDone)
+
+	for i := len(started) - 1; i >= 0; i-- {
+		c := started[i]
+		if c.Telemetry {
+			endSpan(report)
+		}
+
+		componentBegin := time.Now()
+		hookCtx, componentSpan := tracer.Start(ctx, "stop "+c.Name, trace.WithAttributes(attribute.String("graceful.component", c.Name)))
+		cancelFlush := func() {}
+		if c.Telemetry {
+			hookCtx, cancelFlush = flushContext(hookCtx, m.flushTimeout)
+		}
+		err := runHook(hookCtx, c.StopTimeout, c.Stop)
+		cancelFlush()
+		if err != nil {
+			componentSpan.RecordError(err)
+			componentSpan.SetStatus(codes.Error, err.Error())
+		}
+		componentSpan.End()
+		left.Add(-1)
+
+		result := ComponentReport{Name: c.Name, Duration: time.Since(componentBegin), Err: err}
+		report.Components = append(report.Components, result)
+
+		if err != nil {
+			zap.L().Error("Component failed to stop", zap.String("component", c.Name), zap.Duration("duration", result.Duration), zap.Error(err))
+		} else {
+			zap.L().Info("Component stopped", zap.String("component", c.Name), zap.Duration("duration", result.Duration))
+		}
+	}
+	report.Duration = time.Since(begin)
+	endSpan(report)
+
+	if failed := report.Failed(); len(failed) > 0 {
+		zap.L().Error("Shutdown finished with failures", zap.Duration("duration", report.Duration), zap.Strings("components.failed", componentNames(failed)))
+	} else {
+		zap.L().Info("Shutdown finished", zap.Duration("duration", report.Duration))
+	}
+	return report
+}
+
+// logProgress logs the components left to stop and the requests they are
+// serving every progress interval until done is closed. The components
+// stop from the end of started, left of them remain.
+func (m *Manager) logProgress(begin time.Time, started []Component, left *atomic.Int64, done <-chan struct{}) {
+	if m.progressInterval <= 0 {
 		return
 	}
+	ticker := time.NewTicker(m.progressInterval)
+	defer ticker.Stop()
 
-	time.Sleep(time.Duration(10 * time.Second))
-	logger.Info("system shutting down gracefully")
-	return
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		remaining := started[:left.Load()]
+		names := make([]string, 0, len(remaining))
+		var inFlight int64
+		for i := len(remaining) - 1; i >= 0; i-- {
+			names = append(names, remaining[i].Name)
+			if remaining[i].InFlight != nil {
+				inFlight += remaining[i].InFlight()
+			}
+		}
+		zap.L().Info("Shutdown in progress",
+			zap.Duration("elapsed", time.Since(begin)),
+			zap.Strings("components.remaining", names),
+			zap.Int64("requests.in_flight", inFlight),
+		)
+	}
+}
+
+// flushContext detaches ctx from the shutdown, so the telemetry is still
+// flushed once ctx is done, ending timeout later with the cause of ctx.
+func flushContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	flushCtx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cancel(context.Cause(ctx))
+		case <-flushCtx.Done():
+		}
+	})
+	return flushCtx, func() {
+		stop()
+		cancel(nil)
+	}
+}
+
+// runHook calls hook, giving up when ctx is done or after timeout. The
+// hook is not called once ctx is done, and keeps running in the background
+// when it is given up on.
+func runHook(ctx context.Context, timeout time.Duration, hook func(context.Context) error) error {
+	if hook == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- hook(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// startOrder sorts the components not in started so that each follows its
+// dependencies, keeping the registration order otherwise.
+func startOrder(components, started []Component) ([]Component, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	byName := make(map[string]Component, len(components))
+	state := make(map[string]int, len(components))
+	for _, c := range components {
+		if _, ok := byName[c.Name]; ok {
+			return nil, fmt.Errorf("graceful: component %q registered twice", c.Name)
+		}
+		byName[c.Name] = c
+	}
+	for _, c := range started {
+		state[c.Name] = visited
+	}
+
+	var order []Component
+	var visit func(c Component) error
+	visit = func(c Component) error {
+		switch state[c.Name] {
+		case visiting:
+			return fmt.Errorf("graceful: dependency cycle through %q", c.Name)
+		case visited:
+			return nil
+		}
+
+		state[c.Name] = visiting
+		for _, name := range c.DependsOn {
+			dep, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("graceful: component %q depends on unknown component %q", c.Name, name)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[c.Name] = visited
+		order = append(order, c)
+		return nil
+	}
+
+	for _, c := range components {
+		if err := visit(c); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
 }
@@ -0,0 +1,303 @@
+package graceful
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestManagerOrder(t *testing.T) {
+	var events eventLog
+	m := New()
+	m.Register(
+		events.component("server", "client", "telemetry"),
+		events.component("telemetry"),
+		events.component("client", "telemetry"),
+		events.component("worker"),
+	)
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	report := m.Stop(context.Background())
+	if err := report.Err(); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+
+	events.assert(t,
+		"start telemetry", "start client", "start server", "start worker",
+		"stop worker", "stop server", "stop client", "stop telemetry",
+	)
+	if got, want := componentNames(report.Components), []string{"worker", "server", "client", "telemetry"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("report components = %v, want %v", got, want)
+	}
+}
+
+func TestManagerStartInvalidDependencies(t *testing.T) {
+	tests := []struct {
+		name       string
+		components []Component
+		want       string
+	}{
+		{
+			name: "cycle",
+			components: []Component{
+				{Name: "a", DependsOn: []string{"b"}},
+				{Name: "b", DependsOn: []string{"c"}},
+				{Name: "c", DependsOn: []string{"a"}},
+			},
+			want: `graceful: dependency cycle through "a"`,
+		},
+		{
+			name:       "self",
+			components: []Component{{Name: "a", DependsOn: []string{"a"}}},
+			want:       `graceful: dependency cycle through "a"`,
+		},
+		{
+			name:       "unknown",
+			components: []Component{{Name: "a", DependsOn: []string{"b"}}},
+			want:       `graceful: component "a" depends on unknown component "b"`,
+		},
+		{
+			name:       "duplicate",
+			components: []Component{{Name: "a"}, {Name: "a"}},
+			want:       `graceful: component "a" registered twice`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var started bool
+			for i := range tt.components {
+				tt.components[i].Start = func(context.Context) error {
+					started = true
+					return nil
+				}
+			}
+			m := New()
+			m.Register(tt.components...)
+
+			err := m.Start(context.Background())
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("start = %v, want %s", err, tt.want)
+			}
+			if started {
+				t.Errorf("components started despite the invalid dependencies")
+			}
+		})
+	}
+}
+
+func TestManagerStartFailure(t *testing.T) {
+	var events eventLog
+	failure := errors.New("no database")
+	database := events.component("database")
+	database.Start = func(context.Context) error { return failure }
+
+	m := New()
+	m.Register(events.component("telemetry"), database, events.component("server", "database"))
+
+	err := m.Start(context.Background())
+	if !errors.Is(err, failure) || !strings.HasPrefix(err.Error(), "graceful: start database: ") {
+		t.Errorf("start = %v, want the database error", err)
+	}
+	// The components started so far are stopped, the others never start.
+	events.assert(t, "start telemetry", "stop telemetry")
+}
+
+func TestManagerHookTimeouts(t *testing.T) {
+	t.Run("start", func(t *testing.T) {
+		release := make(chan struct{})
+		t.Cleanup(func() { close(release) })
+		m := New()
+		m.Register(Component{
+			Name:         "slow",
+			StartTimeout: 10 * time.Millisecond,
+			Start: func(context.Context) error {
+				<-release
+				return nil
+			},
+		})
+
+		if err := m.Start(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("start = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		var events eventLog
+		release := make(chan struct{})
+		t.Cleanup(func() { close(release) })
+		stuck := events.component("stuck")
+		stuck.StopTimeout = 10 * time.Millisecond
+		stuck.Stop = func(context.Context) error {
+			// Ignores its context, the manager gives up on it.
+			<-release
+			return nil
+		}
+
+		m := New()
+		m.Register(events.component("telemetry"), stuck)
+		if err := m.Start(context.Background()); err != nil {
+			t.Fatalf("start: %v", err)
+		}
+
+		report := m.Stop(context.Background())
+		if got := report.Components[0]; got.Name != "stuck" || !got.TimedOut() {
+			t.Errorf("stuck = %+v, want timed out", got)
+		}
+		if got := report.Components[1]; got.Name != "telemetry" || got.Err != nil {
+			t.Errorf("telemetry = %+v, want stopped", got)
+		}
+		events.assert(t, "start telemetry", "start stuck", "stop telemetry")
+	})
+
+	t.Run("shutdown deadline", func(t *testing.T) {
+		flushing := make(chan error, 1)
+		m := New(WithShutdownTimeout(20*time.Millisecond), WithFlushTimeout(20*time.Millisecond))
+		m.Register(Component{
+			Name:      "telemetry",
+			Telemetry: true,
+			// Flushes although the deadline passed, then overruns its flush timeout.
+			Stop: func(ctx context.Context) error {
+				flushing <- ctx.Err()
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		}, Component{
+			Name:        "slow",
+			DependsOn:   []string{"telemetry"},
+			StopTimeout: time.Hour,
+			Stop: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		})
+		if err := m.Start(context.Background()); err != nil {
+			t.Fatalf("start: %v", err)
+		}
+
+		report := m.Stop(context.Background())
+		if got := report.Components[0]; !got.TimedOut() {
+			t.Errorf("slow = %+v, want timed out at the shutdown deadline", got)
+		}
+		if got, err := report.Components[1], <-flushing; !got.TimedOut() || err != nil {
+			t.Errorf("telemetry = %+v, called with %v, want flushing until the flush timeout", got, err)
+		}
+		if report.Duration > time.Second {
+			t.Errorf("shutdown took %s, want the 20ms deadline and flush timeout", report.Duration)
+		}
+	})
+}
+
+func TestReport(t *testing.T) {
+	failure := errors.New("connection reset")
+	m := New()
+	m.Register(
+		Component{Name: "telemetry"},
+		Component{Name: "client", Stop: func(context.Context) error { return failure }},
+		Component{Name: "server", Stop: func(context.Context) error { panic("closed twice") }},
+		Component{Name: "worker", Stop: func(context.Context) error { return nil }},
+	)
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	report := m.Stop(context.Background())
+	if got, want := componentNames(report.Failed()), []string{"server", "client"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("failed = %v, want %v", got, want)
+	}
+
+	err := report.Err()
+	var shutdownErr *ShutdownError
+	if !errors.As(err, &shutdownErr) || shutdownErr.Report != report {
+		t.Fatalf("err = %#v, want a *ShutdownError with the report", err)
+	}
+	if !errors.Is(err, failure) {
+		t.Errorf("err does not wrap the client error")
+	}
+	want := "graceful: 2 of 4 components failed to stop: server: panic: closed twice; client: connection reset"
+	if err.Error() != want {
+		t.Errorf("err = %q, want %q", err, want)
+	}
+
+	if err := (&Report{Components: []ComponentReport{{Name: "telemetry"}}}).Err(); err != nil {
+		t.Errorf("err = %v for a clean shutdown, want nil", err)
+	}
+}
+
+func TestManagerRunComponentFailure(t *testing.T) {
+	var events eventLog
+	failure := errors.New("listener closed")
+	server := events.component("server")
+	server.Run = func() error { return failure }
+
+	m := New()
+	m.Register(events.component("telemetry"), server)
+
+	err := m.Run(context.Background())
+	if !errors.Is(err, failure) {
+		t.Errorf("run = %v, want the server error", err)
+	}
+	events.assert(t, "start telemetry", "start server", "stop server", "stop telemetry")
+}
+
+func TestManagerRunContextDone(t *testing.T) {
+	var events eventLog
+	ctx, cancel := context.WithCancel(context.Background())
+	m := New()
+	m.Register(events.component("telemetry"), Component{
+		Name: "canceller",
+		Run: func() error {
+			cancel()
+			return nil
+		},
+	})
+
+	if err := m.Run(ctx); err != nil {
+		t.Errorf("run = %v, want nil", err)
+	}
+	events.assert(t, "start telemetry", "stop telemetry")
+}
+
+// eventLog records the start and stop of components.
+type eventLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *eventLog) add(event string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+}
+
+// component returns a component recording its start and stop in l.
+func (l *eventLog) component(name string, dependsOn ...string) Component {
+	return Component{
+		Name:      name,
+		DependsOn: dependsOn,
+		Start: func(context.Context) error {
+			l.add("start " + name)
+			return nil
+		},
+		Stop: func(context.Context) error {
+			l.add("stop " + name)
+			return nil
+		},
+	}
+}
+
+func (l *eventLog) assert(t *testing.T, want ...string) {
+	t.Helper()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !reflect.DeepEqual(l.events, want) {
+		t.Errorf("events = %q, want %q", l.events, want)
+	}
+}
@@ -0,0 +1,83 @@
+package graceful
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Report describes a shutdown.
+type Report struct {
+	// Components lists the components in the order they were stopped.
+	Components []ComponentReport
+	Duration   time.Duration
+}
+
+// ComponentReport is the outcome of stopping one component.
+type ComponentReport struct {
+	Name     string
+	Duration time.Duration
+	// Err is the error returned by Stop, or the context error when Stop
+	// did not return in time.
+	Err error
+}
+
+// TimedOut reports whether Stop did not return before its timeout or the
+// shutdown deadline.
+func (c ComponentReport) TimedOut() bool {
+	return errors.Is(c.Err, context.DeadlineExceeded)
+}
+
+// Failed returns the components that failed to stop.
+func (r *Report) Failed() []ComponentReport {
+	var failed []ComponentReport
+	for _, c := range r.Components {
+		if c.Err != nil {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// Err returns a *ShutdownError when components failed to stop, nil
+// otherwise.
+func (r *Report) Err() error {
+	if len(r.Failed()) == 0 {
+		return nil
+	}
+	return &ShutdownError{Report: r}
+}
+
+// ShutdownError is returned when components failed to stop.
+type ShutdownError struct {
+	Report *Report
+}
+
+func (e *ShutdownError) Error() string {
+	failed := e.Report.Failed()
+	reasons := make([]string, 0, len(failed))
+	for _, c := range failed {
+		reasons = append(reasons, fmt.Sprintf("%s: %v", c.Name, c.Err))
+	}
+	return fmt.Sprintf("graceful: %d of %d components failed to stop: %s",
+		len(failed), len(e.Report.Components), strings.Join(reasons, "; "))
+}
+
+// Unwrap returns the errors of the components that failed to stop.
+func (e *ShutdownError) Unwrap() []error {
+	var errs []error
+	for _, c := range e.Report.Failed() {
+		errs = append(errs, c.Err)
+	}
+	return errs
+}
+
+func componentNames(components []ComponentReport) []string {
+	names := make([]string, 0, len(components))
+	for _, c := range components {
+		names = append(names, c.Name)
+	}
+	return names
+}
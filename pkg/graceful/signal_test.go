@@ -0,0 +1,78 @@
+//go:build unix
+
+package graceful
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+)
+
+func TestManagerRunRepeatedSignal(t *testing.T) {
+	started := make(chan struct{})
+	stopping := make(chan struct{})
+	var workerStopped bool
+	var flushErr error
+
+	m := New(WithSignals(syscall.SIGUSR1))
+	m.Register(Component{
+		Name:      "telemetry",
+		Telemetry: true,
+		// Flushes although the shutdown was forced.
+		Stop: func(ctx context.Context) error {
+			flushErr = ctx.Err()
+			return nil
+		},
+	}, Component{
+		Name:      "worker",
+		DependsOn: []string{"telemetry"},
+		Stop: func(context.Context) error {
+			workerStopped = true
+			return nil
+		},
+	}, Component{
+		Name:      "server",
+		DependsOn: []string{"worker"},
+		Start: func(context.Context) error {
+			close(started)
+			return nil
+		},
+		// Waits for its requests until the shutdown is forced.
+		Stop: func(ctx context.Context) error {
+			close(stopping)
+			<-ctx.Done()
+			return context.Cause(ctx)
+		},
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- m.Run(context.Background()) }()
+
+	<-started
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("signal: %v", err)
+	}
+	<-stopping
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("signal: %v", err)
+	}
+
+	err := <-done
+	if !errors.Is(err, ErrForcedShutdown) {
+		t.Errorf("run = %v, want %v", err, ErrForcedShutdown)
+	}
+	var shutdownErr *ShutdownError
+	if !errors.As(err, &shutdownErr) {
+		t.Fatalf("run = %v, want a *ShutdownError", err)
+	}
+	if got := shutdownErr.Report.Components[0]; got.Name != "server" || got.TimedOut() {
+		t.Errorf("server = %+v, want given up on without timing out", got)
+	}
+	if got := shutdownErr.Report.Components[1]; workerStopped || !errors.Is(got.Err, ErrForcedShutdown) {
+		t.Errorf("worker = %+v, stopped %t, want given up on without stopping", got, workerStopped)
+	}
+	if got := shutdownErr.Report.Components[2]; got.Err != nil || flushErr != nil {
+		t.Errorf("telemetry = %+v, flushed with %v, want flushed", got, flushErr)
+	}
+}
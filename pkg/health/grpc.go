@@ -0,0 +1,86 @@
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// grpcService is the grpc.health.v1 service of one gRPC server.
+type grpcService struct {
+	server *grpchealth.Server
+	// services lists the services of the gRPC server, reported along with
+	// the overall status of the empty service name.
+	services []string
+}
+
+func (s *grpcService) set(status healthpb.HealthCheckResponse_ServingStatus) {
+	s.server.SetServingStatus("", status)
+	for _, name := range s.services {
+		s.server.SetServingStatus(name, status)
+	}
+}
+
+// shutdown sets all services NOT_SERVING and ignores later updates.
+func (s *grpcService) shutdown() {
+	s.server.Shutdown()
+}
+
+// resume accepts updates again after shutdown.
+func (s *grpcService) resume() {
+	s.server.Resume()
+}
+
+// RegisterGRPC registers the grpc.health.v1 service on server, reporting
+// the readiness for the whole server and each service registered so far.
+// It must be called after the other services are registered and before
+// serving.
+func (h *Health) RegisterGRPC(server *grpc.Server) {
+	s := &grpcService{server: grpchealth.NewServer()}
+	for name := range server.GetServiceInfo() {
+		s.services = append(s.services, name)
+	}
+	healthpb.RegisterHealthServer(server, s.server)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.grpc = append(h.grpc, s)
+	if h.shuttingDown {
+		s.shutdown()
+		return
+	}
+	s.set(healthpb.HealthCheckResponse_NOT_SERVING)
+}
+
+// publish sets the status of the gRPC health services from report.
+func (h *Health) publish(report Report) {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if report.Ready {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+
+	h.mu.Lock()
+	services := h.grpc
+	h.mu.Unlock()
+	for _, s := range services {
+		s.set(status)
+	}
+}
+
+// GRPCClientCheck fails while conn cannot reach its target. An idle
+// connection is asked to connect, so the next check sees the outcome.
+func GRPCClientCheck(conn *grpc.ClientConn) Check {
+	return func(context.Context) error {
+		switch state := conn.GetState(); state {
+		case connectivity.Idle:
+			conn.Connect()
+		case connectivity.TransientFailure, connectivity.Shutdown:
+			return fmt.Errorf("connection to %s is %s", conn.Target(), state)
+		}
+		return nil
+	}
+}
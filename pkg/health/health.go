@@ -0,0 +1,241 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const defaultCheckTimeout = 2 * time.Second
+
+// Check reports an error while a dependency of the process is not usable,
+// e.g. a client connection that cannot reach its server.
+type Check func(ctx context.Context) error
+
+// Option configures a Health.
+type Option func(*Health)
+
+// WithCheckTimeout bounds each check, 2 seconds by default. A check still
+// running then fails.
+func WithCheckTimeout(timeout time.Duration) Option {
+	return func(h *Health) {
+		h.checkTimeout = timeout
+	}
+}
+
+// WithShutdownDelay makes Shutdown wait delay once the process is marked
+// not ready, so load balancers probing /readyz stop sending requests before
+// the servers close their listeners. There is no delay by default.
+func WithShutdownDelay(delay time.Duration) Option {
+	return func(h *Health) {
+		h.shutdownDelay = delay
+	}
+}
+
+// Health reports the liveness and readiness of the process on /healthz and
+// /readyz and through the grpc.health.v1 service. The process is ready
+// between Start and Shutdown while all checks pass.
+type Health struct {
+	checkTimeout  time.Duration
+	shutdownDelay time.Duration
+
+	mu           sync.Mutex
+	checks       []namedCheck
+	started      bool
+	shuttingDown bool
+	grpc         []*grpcService
+	stopWatch    context.CancelFunc
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// New returns a Health without checks, not ready until Start.
+func New(opts ...Option) *Health {
+	h := &Health{checkTimeout: defaultCheckTimeout}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// AddCheck adds a check failing the readiness. Checks run in the order they
+// are added on each readiness probe.
+func (h *Health) AddCheck(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// Start marks the process ready once the servers accept requests and runs
+// the checks every interval to publish the readiness to the gRPC health
+// services.
+func (h *Health) Start(interval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	h.mu.Lock()
+	h.started = true
+	h.shuttingDown = false
+	h.stopWatch = cancel
+	services := h.grpc
+	h.mu.Unlock()
+
+	for _, s := range services {
+		s.resume()
+	}
+
+	h.publish(h.Ready(ctx))
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				h.publish(h.Ready(ctx))
+			}
+		}
+	}()
+}
+
+// Shutdown marks the process not ready for good, so load balancers stop
+// sending requests while the servers drain. It then waits for the shutdown
+// delay, or until ctx is done and returns its cause.
+func (h *Health) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.shuttingDown = true
+	stopWatch := h.stopWatch
+	h.stopWatch = nil
+	services := h.grpc
+	h.mu.Unlock()
+
+	if stopWatch != nil {
+		stopWatch()
+	}
+	for _, s := range services {
+		s.shutdown()
+	}
+	zap.L().Info("Readiness changed to not serving", zap.Duration("delay", h.shutdownDelay))
+
+	if h.shutdownDelay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(h.shutdownDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// Report is the outcome of a readiness probe.
+type Report struct {
+	Ready bool `json:"ready"`
+	// Status is "starting", "serving", "not_serving" or "shutting_down".
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// CheckResult is the outcome of one check.
+type CheckResult struct {
+	Name     string        `json:"name"`
+	Healthy  bool          `json:"healthy"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Ready runs the checks and reports whether the process accepts requests.
+// The checks are skipped before Start and after Shutdown.
+func (h *Health) Ready(ctx context.Context) Report {
+	h.mu.Lock()
+	started, shuttingDown := h.started, h.shuttingDown
+	checks := h.checks
+	h.mu.Unlock()
+
+	report := Report{Checks: []CheckResult{}}
+	switch {
+	case shuttingDown:
+		report.Status = "shutting_down"
+		return report
+	case !started:
+		report.Status = "starting"
+		return report
+	}
+
+	report.Ready = true
+	for _, c := range checks {
+		result := h.run(ctx, c)
+		report.Ready = report.Ready && result.Healthy
+		report.Checks = append(report.Checks, result)
+	}
+	report.Status = "serving"
+	if !report.Ready {
+		report.Status = "not_serving"
+	}
+	return report
+}
+
+func (h *Health) run(ctx context.Context, c namedCheck) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.checkTimeout)
+	defer cancel()
+
+	begin := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- c.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{Name: c.name, Healthy: err == nil, Duration: time.Since(begin)}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LivenessHandler returns the handler of /healthz. It answers 200 OK as
+// long as the process serves requests, including while it shuts down, so
+// an orchestrator does not restart it for a failing dependency.
+func (h *Health) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"ok"}` + "\n"))
+	})
+}
+
+// ReadinessHandler returns the handler of /readyz serving the Report as
+// JSON. It answers 503 Service Unavailable while the process is not ready.
+func (h *Health) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := h.Ready(r.Context())
+
+		w.Header().Set("Content-Type", "application/json")
+		if !report.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	})
+}
@@ -0,0 +1,246 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestReady(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	tests := []struct {
+		name   string
+		check  Check
+		want   bool
+		status string
+		err    string
+	}{
+		{
+			name:   "passing",
+			check:  func(context.Context) error { return nil },
+			want:   true,
+			status: "serving",
+		},
+		{
+			name:   "failing",
+			check:  func(context.Context) error { return errors.New("no connection") },
+			status: "not_serving",
+			err:    "no connection",
+		},
+		{
+			name: "timeout",
+			check: func(context.Context) error {
+				<-release
+				return nil
+			},
+			status: "not_serving",
+			err:    context.DeadlineExceeded.Error(),
+		},
+		{
+			name:   "panic",
+			check:  func(context.Context) error { panic("nil connection") },
+			status: "not_serving",
+			err:    "panic: nil connection",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(WithCheckTimeout(10 * time.Millisecond))
+			h.AddCheck("first", func(context.Context) error { return nil })
+			h.AddCheck("second", tt.check)
+			startHealth(t, h)
+
+			report := h.Ready(context.Background())
+			if report.Ready != tt.want || report.Status != tt.status {
+				t.Errorf("ready = %t %q, want %t %q", report.Ready, report.Status, tt.want, tt.status)
+			}
+			if len(report.Checks) != 2 || report.Checks[0].Name != "first" || !report.Checks[0].Healthy {
+				t.Fatalf("checks = %+v, want first healthy then second", report.Checks)
+			}
+			second := report.Checks[1]
+			if second.Name != "second" || second.Healthy != (tt.err == "") || second.Error != tt.err {
+				t.Errorf("second = %+v, want error %q", second, tt.err)
+			}
+		})
+	}
+}
+
+func TestReadyLifecycle(t *testing.T) {
+	var checked int
+	h := New()
+	h.AddCheck("counted", func(context.Context) error {
+		checked++
+		return nil
+	})
+
+	if report := h.Ready(context.Background()); report.Ready || report.Status != "starting" {
+		t.Errorf("before start = %t %q, want not ready and starting", report.Ready, report.Status)
+	}
+
+	startHealth(t, h)
+	if report := h.Ready(context.Background()); !report.Ready || report.Status != "serving" {
+		t.Errorf("after start = %t %q, want ready and serving", report.Ready, report.Status)
+	}
+
+	if err := h.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	checked = 0
+	if report := h.Ready(context.Background()); report.Ready || report.Status != "shutting_down" {
+		t.Errorf("after shutdown = %t %q, want not ready and shutting_down", report.Ready, report.Status)
+	}
+	if checked != 0 {
+		t.Errorf("checks ran after shutdown")
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	failing := errors.New("no connection")
+	var err error
+	h := New()
+	h.AddCheck("grpc-client", func(context.Context) error { return err })
+	startHealth(t, h)
+
+	for _, tt := range []struct {
+		err        error
+		wantStatus int
+	}{
+		{nil, http.StatusOK},
+		{failing, http.StatusServiceUnavailable},
+	} {
+		err = tt.err
+
+		rec := httptest.NewRecorder()
+		h.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if rec.Code != tt.wantStatus {
+			t.Errorf("readyz with %v = %d, want %d", tt.err, rec.Code, tt.wantStatus)
+		}
+		var report Report
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("decode readyz: %v", err)
+		}
+		if report.Ready != (tt.err == nil) || len(report.Checks) != 1 {
+			t.Errorf("readyz with %v = %+v", tt.err, report)
+		}
+
+		// Liveness does not depend on the checks.
+		rec = httptest.NewRecorder()
+		h.LivenessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("healthz with %v = %d, want 200", tt.err, rec.Code)
+		}
+	}
+}
+
+func TestShutdownDelay(t *testing.T) {
+	t.Run("waits", func(t *testing.T) {
+		h := New(WithShutdownDelay(50 * time.Millisecond))
+		startHealth(t, h)
+
+		begin := time.Now()
+		if err := h.Shutdown(context.Background()); err != nil {
+			t.Fatalf("shutdown: %v", err)
+		}
+		if elapsed := time.Since(begin); elapsed < 50*time.Millisecond {
+			t.Errorf("shutdown returned after %s, want the 50ms delay", elapsed)
+		}
+		if report := h.Ready(context.Background()); report.Ready {
+			t.Errorf("ready during the shutdown delay")
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		h := New(WithShutdownDelay(time.Hour))
+		startHealth(t, h)
+
+		forced := errors.New("forced")
+		ctx, cancel := context.WithCancelCause(context.Background())
+		cancel(forced)
+		if err := h.Shutdown(ctx); !errors.Is(err, forced) {
+			t.Errorf("shutdown = %v, want %v", err, forced)
+		}
+	})
+}
+
+func TestGRPCHealth(t *testing.T) {
+	var failing error
+	h := New()
+	h.AddCheck("span-queue", func(context.Context) error { return failing })
+
+	server := grpc.NewServer()
+	const service = "notification.NotificationService"
+	server.RegisterService(&grpc.ServiceDesc{ServiceName: service, HandlerType: (*any)(nil)}, struct{}{})
+	h.RegisterGRPC(server)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() { _ = server.Serve(ln) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	client := healthpb.NewHealthClient(conn)
+
+	assertStatus := func(want healthpb.HealthCheckResponse_ServingStatus) {
+		t.Helper()
+		for _, name := range []string{"", service} {
+			resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: name})
+			if err != nil {
+				t.Fatalf("check %q: %v", name, err)
+			}
+			if resp.GetStatus() != want {
+				t.Errorf("status of %q = %v, want %v", name, resp.GetStatus(), want)
+			}
+		}
+	}
+
+	assertStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+	h.Start(time.Hour)
+	assertStatus(healthpb.HealthCheckResponse_SERVING)
+
+	failing = errors.New("span queue full")
+	h.publish(h.Ready(context.Background()))
+	assertStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+
+	failing = nil
+	h.publish(h.Ready(context.Background()))
+	assertStatus(healthpb.HealthCheckResponse_SERVING)
+
+	if err := h.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	assertStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+
+	// Later updates are ignored once shut down.
+	h.publish(Report{Ready: true})
+	assertStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+}
+
+// startHealth starts h, shutting it down at the end of the test.
+func startHealth(t *testing.T, h *Health) {
+	t.Helper()
+
+	h.Start(time.Hour)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_ = h.Shutdown(ctx)
+	})
+}
@@ -0,0 +1,122 @@
+// Package notificationmetrics records the notification metrics shared by
+// the notification client and server.
+package notificationmetrics
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"google.golang.org/grpc/status"
+)
+
+// Channels and transports of a notification.
+const (
+	ChannelEmail = "email"
+	ChannelPush  = "push"
+
+	TransportHTTP = "http"
+	TransportGRPC = "grpc"
+)
+
+// Attribute keys are limited to bounded values to keep the number of series
+// per instrument small.
+var (
+	channelKey   = attribute.Key("notification.channel")
+	transportKey = attribute.Key("notification.transport")
+	errorTypeKey = attribute.Key("error.type")
+)
+
+// Metrics are the instruments recording notification deliveries.
+type Metrics struct {
+	requested   metric.Int64Counter
+	sent        metric.Int64Counter
+	failed      metric.Int64Counter
+	duration    metric.Float64Histogram
+	inFlight    metric.Int64UpDownCounter
+	payloadSize metric.Int64Histogram
+}
+
+// New creates the instruments from the meter named meterName, the import
+// path of the package delivering the notifications.
+func New(meterName string) *Metrics {
+	meter := otel.Meter(meterName)
+	m := &Metrics{}
+
+	var err error
+	m.requested, err = meter.Int64Counter("notification.requested",
+		metric.WithDescription("Notifications requested for delivery."),
+		metric.WithUnit("{notification}"))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	m.sent, err = meter.Int64Counter("notification.sent",
+		metric.WithDescription("Notifications delivered successfully."),
+		metric.WithUnit("{notification}"))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	m.failed, err = meter.Int64Counter("notification.failed",
+		metric.WithDescription("Notifications that could not be delivered."),
+		metric.WithUnit("{notification}"))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	m.duration, err = meter.Float64Histogram("notification.send.duration",
+		metric.WithDescription("Time spent delivering a notification."),
+		metric.WithUnit("s"))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	m.inFlight, err = meter.Int64UpDownCounter("notification.inflight",
+		metric.WithDescription("Notifications currently being delivered."),
+		metric.WithUnit("{notification}"))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	m.payloadSize, err = meter.Int64Histogram("notification.payload.size",
+		metric.WithDescription("Size of the notification request payload."),
+		metric.WithUnit("By"),
+		metric.WithExplicitBucketBoundaries(64, 256, 1024, 4096, 16384, 65536))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return m
+}
+
+// Start records a notification request and returns the function recording
+// its outcome.
+func (m *Metrics) Start(ctx context.Context, channel, transport string, payloadSize int) func(err error) {
+	attrs := metric.WithAttributes(channelKey.String(channel), transportKey.String(transport))
+	begin := time.Now()
+
+	m.requested.Add(ctx, 1, attrs)
+	m.inFlight.Add(ctx, 1, attrs)
+	m.payloadSize.Record(ctx, int64(payloadSize), attrs)
+
+	return func(err error) {
+		m.inFlight.Add(ctx, -1, attrs)
+		m.duration.Record(ctx, time.Since(begin).Seconds(), attrs)
+		if err != nil {
+			m.failed.Add(ctx, 1, attrs, metric.WithAttributes(errorTypeKey.String(errorType(err))))
+			return
+		}
+		m.sent.Add(ctx, 1, attrs)
+	}
+}
+
+// errorType classifies err by its gRPC status code, which is bounded.
+func errorType(err error) string {
+	if s, ok := status.FromError(err); ok {
+		return s.Code().String()
+	}
+	return "_OTHER"
+}
@@ -0,0 +1,204 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	baggageAttributePrefix = "baggage."
+
+	hashPrefix = "hmac:"
+	// hashLength is the number of bytes of the HMAC kept by HashValue.
+	hashLength = 8
+
+	defaultBaggageMaxMembers     = 8
+	defaultBaggageMaxValueLength = 128
+)
+
+// RedactFunc replaces a sensitive value before it is exported.
+type RedactFunc func(value string) string
+
+// MaskValue replaces every character but the last four with '*'.
+func MaskValue(value string) string {
+	if len(value) <= 4 {
+		return strings.Repeat("*", len(value))
+	}
+	return strings.Repeat("*", len(value)-4) + value[len(value)-4:]
+}
+
+// HashValue replaces value with a short HMAC-SHA256, keeping equal values
+// correlatable without revealing them. It is keyed by WithHashKey, or by a
+// random key generated once per process. Hashed values are returned as is,
+// so a value matched by several rules is hashed once.
+func HashValue(value string) string {
+	if isHashed(value) {
+		return value
+	}
+	key := loadState().hashKey
+	if key == nil {
+		key = processHashKey()
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hashPrefix + hex.EncodeToString(mac.Sum(nil)[:hashLength])
+}
+
+// processHashKey keys HashValue when no key is configured.
+var processHashKey = sync.OnceValue(func() []byte {
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		otel.Handle(err)
+	}
+	return key
+})
+
+// isHashed reports whether value was returned by HashValue.
+func isHashed(value string) bool {
+	digest, ok := strings.CutPrefix(value, hashPrefix)
+	if !ok || len(digest) != hex.EncodedLen(hashLength) {
+		return false
+	}
+	_, err := hex.DecodeString(digest)
+	return err == nil
+}
+
+// BaggageConfig selects the baggage members promoted to span and log
+// attributes, named "baggage.<key>".
+type BaggageConfig struct {
+	// AllowedKeys lists the members that are promoted. Other members are
+	// propagated but never recorded.
+	AllowedKeys []string
+	// MaxMembers bounds the number of members promoted per span or record.
+	MaxMembers int
+	// MaxValueLength truncates longer values.
+	MaxValueLength int
+	// Redact maps a member key to the function applied to its value. Keys
+	// without an entry are redacted by the DefaultRedactionRules matching
+	// "baggage.<key>", e.g. user_id is hashed. A nil function keeps the
+	// value.
+	Redact map[string]RedactFunc
+}
+
+type baggagePromoter struct {
+	cfg BaggageConfig
+}
+
+func newBaggagePromoter(cfg BaggageConfig) *baggagePromoter {
+	if cfg.MaxMembers <= 0 {
+		cfg.MaxMembers = defaultBaggageMaxMembers
+	}
+	if cfg.MaxValueLength <= 0 {
+		cfg.MaxValueLength = defaultBaggageMaxValueLength
+	}
+	return &baggagePromoter{cfg: cfg}
+}
+
+// members calls fn for the allowed members of the baggage in ctx, in
+// AllowedKeys order, with limits and redaction applied.
+func (p *baggagePromoter) members(ctx context.Context, fn func(key, value string)) {
+	bag := baggage.FromContext(ctx)
+	if bag.Len() == 0 {
+		return
+	}
+
+	n := 0
+	for _, key := range p.cfg.AllowedKeys {
+		if n >= p.cfg.MaxMembers {
+			return
+		}
+		member := bag.Member(key)
+		if member.Key() == "" {
+			continue
+		}
+
+		value := member.Value()
+		redact, ok := p.cfg.Redact[key]
+		if !ok {
+			redact = defaultRedactor.lookup(baggageAttributePrefix + key)
+		}
+		if redact != nil {
+			value = redact(value)
+		}
+		if len(value) > p.cfg.MaxValueLength {
+			value = value[:p.cfg.MaxValueLength]
+		}
+		fn(key, value)
+		n++
+	}
+}
+
+// NewBaggageSpanProcessor returns a span processor adding the allowed baggage
+// members of the parent context to every started span.
+func NewBaggageSpanProcessor(cfg BaggageConfig) trace.SpanProcessor {
+	return &baggageSpanProcessor{promoter: newBaggagePromoter(cfg)}
+}
+
+type baggageSpanProcessor struct {
+	promoter *baggagePromoter
+}
+
+func (p *baggageSpanProcessor) OnStart(parent context.Context, s trace.ReadWriteSpan) {
+	p.promoter.members(parent, func(key, value string) {
+		s.SetAttributes(attribute.String(baggageAttributePrefix+key, value))
+	})
+}
+
+func (p *baggageSpanProcessor) OnEnd(trace.ReadOnlySpan) {}
+
+func (p *baggageSpanProcessor) ForceFlush(context.Context) error { return nil }
+
+func (p *baggageSpanProcessor) Shutdown(context.Context) error { return nil }
+
+// NewBaggageLogProcessor returns a log processor adding the allowed baggage
+// members of the emitting context to every record. It must be registered
+// before the exporting processor.
+func NewBaggageLogProcessor(cfg BaggageConfig) log.Processor {
+	return &baggageLogProcessor{promoter: newBaggagePromoter(cfg)}
+}
+
+type baggageLogProcessor struct {
+	promoter *baggagePromoter
+}
+
+func (p *baggageLogProcessor) OnEmit(ctx context.Context, record *log.Record) error {
+	p.promoter.members(ctx, func(key, value string) {
+		record.AddAttributes(otellog.String(baggageAttributePrefix+key, value))
+	})
+	return nil
+}
+
+func (p *baggageLogProcessor) ForceFlush(context.Context) error { return nil }
+
+func (p *baggageLogProcessor) Shutdown(context.Context) error { return nil }
+
+// ContextWithBaggage returns ctx with the given key/value pairs added to its
+// baggage. Pairs with an empty value are skipped.
+func ContextWithBaggage(ctx context.Context, keyValues ...string) (context.Context, error) {
+	bag := baggage.FromContext(ctx)
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		if keyValues[i+1] == "" {
+			continue
+		}
+		member, err := baggage.NewMemberRaw(keyValues[i], keyValues[i+1])
+		if err != nil {
+			return ctx, err
+		}
+		if bag, err = bag.SetMember(member); err != nil {
+			return ctx, err
+		}
+	}
+	return baggage.ContextWithBaggage(ctx, bag), nil
+}
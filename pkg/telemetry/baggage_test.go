@@ -0,0 +1,116 @@
+package telemetry
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestBaggageProcessors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  BaggageConfig
+		bag  []string
+		want map[string]string
+	}{
+		{
+			name: "allowed keys",
+			cfg:  BaggageConfig{AllowedKeys: []string{"tenant_id", "origin"}},
+			bag:  []string{"tenant_id", "acme", "origin", "web", "session", "s-1"},
+			want: map[string]string{"baggage.tenant_id": "acme", "baggage.origin": "web"},
+		},
+		{
+			name: "missing members",
+			cfg:  BaggageConfig{AllowedKeys: []string{"tenant_id", "origin"}},
+			bag:  []string{"origin", "web"},
+			want: map[string]string{"baggage.origin": "web"},
+		},
+		{
+			name: "max members",
+			cfg:  BaggageConfig{AllowedKeys: []string{"tenant_id", "origin", "channel"}, MaxMembers: 2},
+			bag:  []string{"channel", "push", "origin", "web", "tenant_id", "acme"},
+			want: map[string]string{"baggage.tenant_id": "acme", "baggage.origin": "web"},
+		},
+		{
+			name: "max value length",
+			cfg:  BaggageConfig{AllowedKeys: []string{"tenant_id"}, MaxValueLength: 4},
+			bag:  []string{"tenant_id", "acme-corporation"},
+			want: map[string]string{"baggage.tenant_id": "acme"},
+		},
+		{
+			name: "default max value length",
+			cfg:  BaggageConfig{AllowedKeys: []string{"tenant_id"}},
+			bag:  []string{"tenant_id", strings.Repeat("a", 2*defaultBaggageMaxValueLength)},
+			want: map[string]string{"baggage.tenant_id": strings.Repeat("a", defaultBaggageMaxValueLength)},
+		},
+		{
+			name: "no baggage",
+			cfg:  BaggageConfig{AllowedKeys: []string{"tenant_id"}},
+			want: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, err := ContextWithBaggage(context.Background(), tt.bag...)
+			if err != nil {
+				t.Fatalf("baggage: %v", err)
+			}
+
+			spans := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(
+				sdktrace.WithSpanProcessor(NewBaggageSpanProcessor(tt.cfg)),
+				sdktrace.WithSpanProcessor(spans),
+			)
+			t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+			_, span := provider.Tracer("baggage-test").Start(ctx, "promoted")
+			span.End()
+
+			got := map[string]string{}
+			for _, attr := range spans.Ended()[0].Attributes() {
+				got[string(attr.Key)] = attr.Value.Emit()
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("span attributes = %v, want %v", got, tt.want)
+			}
+
+			logs := &logRecorder{}
+			loggerProvider := sdklog.NewLoggerProvider(
+				sdklog.WithProcessor(NewBaggageLogProcessor(tt.cfg)),
+				sdklog.WithProcessor(logs),
+			)
+			t.Cleanup(func() { _ = loggerProvider.Shutdown(context.Background()) })
+			loggerProvider.Logger("baggage-test").Emit(ctx, otellog.Record{})
+
+			got = map[string]string{}
+			logs.get()[0].WalkAttributes(func(attr otellog.KeyValue) bool {
+				got[attr.Key] = attr.Value.AsString()
+				return true
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("log attributes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContextWithBaggage(t *testing.T) {
+	ctx, err := ContextWithBaggage(context.Background(), "tenant_id", "acme", "origin", "")
+	if err != nil {
+		t.Fatalf("baggage: %v", err)
+	}
+	var got []attribute.KeyValue
+	newBaggagePromoter(BaggageConfig{AllowedKeys: []string{"tenant_id", "origin"}}).members(ctx, func(key, value string) {
+		got = append(got, attribute.String(key, value))
+	})
+	if want := []attribute.KeyValue{attribute.String("tenant_id", "acme")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("members = %v, want %v, skipping the empty value", got, want)
+	}
+}
@@ -0,0 +1,79 @@
+package telemetry
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Defaults of WithErrorLogRate.
+const (
+	defaultErrorLogBurst    = 10
+	defaultErrorLogInterval = time.Minute
+)
+
+// errorHandler is the otel.ErrorHandler installed by SetupOTelSDK. It logs
+// the errors of the SDK, exporters and instrumentation through the global
+// zap logger instead of the standard library logger. At most burst errors
+// are logged per interval, so a collector that is down does not flood the
+// logs, or the log exporter feeding the same collector. The number of
+// errors suppressed in between is added to the next logged one.
+type errorHandler struct {
+	burst    int
+	interval time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	windowCount int
+	suppressed  int
+
+	logged          uint64
+	suppressedTotal uint64
+}
+
+func newErrorHandler(burst int, interval time.Duration) *errorHandler {
+	return &errorHandler{burst: burst, interval: interval}
+}
+
+// Handle logs err unless the rate limit is reached. Nil errors are ignored.
+func (h *errorHandler) Handle(err error) {
+	if err == nil {
+		return
+	}
+
+	h.mu.Lock()
+	if h.burst > 0 && h.interval > 0 {
+		now := time.Now()
+		if now.Sub(h.windowStart) >= h.interval {
+			h.windowStart = now
+			h.windowCount = 0
+		}
+		if h.windowCount >= h.burst {
+			h.suppressed++
+			h.suppressedTotal++
+			h.mu.Unlock()
+			return
+		}
+		h.windowCount++
+	}
+	suppressed := h.suppressed
+	h.suppressed = 0
+	h.logged++
+	h.mu.Unlock()
+
+	fields := []zap.Field{zap.Error(err)}
+	if suppressed > 0 {
+		fields = append(fields, zap.Int("otel.errors.suppressed", suppressed))
+	}
+	// The stack would end in the SDK goroutine reporting the error.
+	zap.L().WithOptions(zap.AddStacktrace(zapcore.FatalLevel)).Error("OpenTelemetry error", fields...)
+}
+
+// counts returns the number of errors logged and suppressed so far.
+func (h *errorHandler) counts() (logged, suppressed uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.logged, h.suppressedTotal
+}
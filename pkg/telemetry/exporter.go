@@ -0,0 +1,168 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutlog"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Exporter selects where a telemetry signal is sent.
+type Exporter string
+
+const (
+	ExporterOTLPGRPC Exporter = "otlp-grpc"
+	ExporterOTLPHTTP Exporter = "otlp-http"
+	ExporterStdout   Exporter = "stdout"
+	ExporterFile     Exporter = "file"
+	ExporterNone     Exporter = "none"
+)
+
+// Environment variables defined by the OpenTelemetry specification.
+// Endpoint, headers, TLS and timeout settings (OTEL_EXPORTER_OTLP_*) are
+// read by the OTLP exporters themselves.
+const (
+	envTracesExporter       = "OTEL_TRACES_EXPORTER"
+	envMetricsExporter      = "OTEL_METRICS_EXPORTER"
+	envLogsExporter         = "OTEL_LOGS_EXPORTER"
+	envOTLPProtocol         = "OTEL_EXPORTER_OTLP_PROTOCOL"
+	envOTLPTracesProtocol   = "OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"
+	envOTLPMetricsProtocol  = "OTEL_EXPORTER_OTLP_METRICS_PROTOCOL"
+	envOTLPLogsProtocol     = "OTEL_EXPORTER_OTLP_LOGS_PROTOCOL"
+	defaultExporterEnvValue = "otlp"
+	exporterPrometheus      = "prometheus"
+	defaultOTLPProtocol     = "http/protobuf"
+)
+
+// exporterFromEnv resolves the exporter of a signal from its
+// OTEL_<SIGNAL>_EXPORTER variable and, for "otlp", from the signal specific
+// or generic OTLP protocol variable.
+func exporterFromEnv(exporterEnv, protocolEnv string) (Exporter, error) {
+	names := exporterNamesFromEnv(exporterEnv)
+
+	// Only the first push exporter is used when a list is given. Prometheus
+	// is a pull exporter and is set up separately.
+	name := string(ExporterNone)
+	for _, n := range names {
+		if n != exporterPrometheus {
+			name = n
+			break
+		}
+	}
+
+	switch name {
+	case "otlp":
+		return otlpExporterFromEnv(protocolEnv)
+	case "console", "stdout":
+		return ExporterStdout, nil
+	case "file":
+		return ExporterFile, nil
+	case "none":
+		return ExporterNone, nil
+	default:
+		return "", fmt.Errorf("telemetry: unsupported %s value %q", exporterEnv, name)
+	}
+}
+
+// exporterNamesFromEnv returns the comma separated exporter names of
+// exporterEnv, defaulting to "otlp".
+func exporterNamesFromEnv(exporterEnv string) []string {
+	value := strings.ToLower(strings.TrimSpace(os.Getenv(exporterEnv)))
+	if value == "" {
+		value = defaultExporterEnvValue
+	}
+
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func otlpExporterFromEnv(protocolEnv string) (Exporter, error) {
+	protocol := os.Getenv(protocolEnv)
+	if protocol == "" {
+		protocol = os.Getenv(envOTLPProtocol)
+	}
+	if protocol == "" {
+		protocol = defaultOTLPProtocol
+	}
+
+	switch strings.ToLower(strings.TrimSpace(protocol)) {
+	case "grpc":
+		return ExporterOTLPGRPC, nil
+	case "http/protobuf":
+		return ExporterOTLPHTTP, nil
+	default:
+		return "", fmt.Errorf("telemetry: unsupported OTLP protocol %q", protocol)
+	}
+}
+
+// newTraceExporter returns the span exporter for kind, or nil for ExporterNone.
+func newTraceExporter(ctx context.Context, kind Exporter, fileCfg FileExporterConfig) (trace.SpanExporter, error) {
+	switch kind {
+	case ExporterOTLPGRPC:
+		return otlptracegrpc.New(ctx)
+	case ExporterOTLPHTTP:
+		return otlptracehttp.New(ctx)
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterFile:
+		return newFileTraceExporter(ctx, fileCfg)
+	case ExporterNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("telemetry: unknown trace exporter %q", kind)
+	}
+}
+
+// newMetricExporter returns the metric exporter for kind, or nil for ExporterNone.
+func newMetricExporter(ctx context.Context, kind Exporter, fileCfg FileExporterConfig) (metric.Exporter, error) {
+	switch kind {
+	case ExporterOTLPGRPC:
+		return otlpmetricgrpc.New(ctx)
+	case ExporterOTLPHTTP:
+		return otlpmetrichttp.New(ctx)
+	case ExporterStdout:
+		return stdoutmetric.New()
+	case ExporterFile:
+		return newFileMetricExporter(fileCfg)
+	case ExporterNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("telemetry: unknown metric exporter %q", kind)
+	}
+}
+
+// newLogExporter returns the log exporter for kind, or nil for ExporterNone.
+func newLogExporter(ctx context.Context, kind Exporter, fileCfg FileExporterConfig) (log.Exporter, error) {
+	switch kind {
+	case ExporterOTLPGRPC:
+		return otlploggrpc.New(ctx)
+	case ExporterOTLPHTTP:
+		return otlploghttp.New(ctx)
+	case ExporterStdout:
+		return stdoutlog.New()
+	case ExporterFile:
+		return newFileLogExporter(fileCfg)
+	case ExporterNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("telemetry: unknown log exporter %q", kind)
+	}
+}
@@ -0,0 +1,116 @@
+package telemetry
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/contrib/otelfiber/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	otelcontrib "go.opentelemetry.io/contrib"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// fiberInstrumentationName is the scope of the otelfiber metrics.
+const fiberInstrumentationName = "github.com/gofiber/contrib/otelfiber"
+
+// FiberMetrics returns a middleware recording the HTTP server metrics of
+// otelfiber, with the same scope and attributes. It must be registered right
+// after otelfiber.Middleware, which is given otelfiber.WithoutMetrics(true):
+// otelfiber records its metrics with the context from before the request
+// span was started, so their histogram points never carry exemplars.
+// Measurements taken here are recorded within the span and link to the
+// trace.
+//
+// server.address is set to serverName, when not empty, rather than to the
+// Host header chosen by the client. Requests for which next returns true are
+// not measured, as with otelfiber.WithNext.
+func FiberMetrics(serverName string, next func(c *fiber.Ctx) bool) fiber.Handler {
+	meter := otel.Meter(fiberInstrumentationName, metric.WithInstrumentationVersion(otelcontrib.Version()))
+
+	duration, err := meter.Float64Histogram(otelfiber.MetricNameHttpServerDuration,
+		metric.WithUnit(otelfiber.UnitMilliseconds),
+		metric.WithDescription("measures the duration inbound HTTP requests"))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	requestSize, err := meter.Int64Histogram(otelfiber.MetricNameHttpServerRequestSize,
+		metric.WithUnit(otelfiber.UnitBytes),
+		metric.WithDescription("measures the size of HTTP request messages"))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	responseSize, err := meter.Int64Histogram(otelfiber.MetricNameHttpServerResponseSize,
+		metric.WithUnit(otelfiber.UnitBytes),
+		metric.WithDescription("measures the size of HTTP response messages"))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	activeRequests, err := meter.Int64UpDownCounter(otelfiber.MetricNameHttpServerActiveRequests,
+		metric.WithUnit(otelfiber.UnitDimensionless),
+		metric.WithDescription("measures the number of concurrent HTTP requests that are currently in-flight"))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return func(c *fiber.Ctx) error {
+		if next != nil && next(c) {
+			return c.Next()
+		}
+
+		ctx := c.UserContext()
+		begin := time.Now()
+		requestAttrs := []attribute.KeyValue{
+			semconv.URLScheme(utils.CopyString(c.Protocol())),
+			semconv.HTTPRequestMethodKey.String(utils.CopyString(c.Method())),
+			semconv.NetworkProtocolName("http"),
+			fiberProtocolVersion(c),
+		}
+		if serverName != "" {
+			requestAttrs = append(requestAttrs, semconv.ServerAddress(serverName))
+		}
+		activeRequests.Add(ctx, 1, metric.WithAttributes(requestAttrs...))
+
+		err := c.Next()
+
+		// The error handler has not run yet, derive the status it will set
+		// the way fiber.DefaultErrorHandler does.
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+
+		responseAttrs := metric.WithAttributes(append(requestAttrs,
+			semconv.HTTPResponseStatusCode(status),
+			semconv.HTTPRoute(c.Route().Path),
+		)...)
+		activeRequests.Add(ctx, -1, metric.WithAttributes(requestAttrs...))
+		var size int64
+		if c.GetRespHeader(fiber.HeaderContentType) != "text/event-stream" {
+			size = int64(len(c.Response().Body()))
+		}
+		duration.Record(ctx, float64(time.Since(begin).Microseconds())/1000, responseAttrs)
+		requestSize.Record(ctx, int64(len(c.Request().Body())), responseAttrs)
+		responseSize.Record(ctx, size, responseAttrs)
+
+		return err
+	}
+}
+
+// fiberProtocolVersion returns the network.protocol.version set by otelfiber.
+func fiberProtocolVersion(c *fiber.Ctx) attribute.KeyValue {
+	if c.Request().Header.IsHTTP11() {
+		return semconv.NetworkProtocolVersion("1.1")
+	}
+	return semconv.NetworkProtocolVersion("1.0")
+}
@@ -0,0 +1,88 @@
+package telemetry
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	otelcontrib "go.opentelemetry.io/contrib"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestFiberMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	setupRuntime(t, WithMetricReader(reader))
+
+	app := fiber.New()
+	app.Use(FiberMetrics("notification.local", func(c *fiber.Ctx) bool { return c.Path() == "/healthz" }))
+	app.Post("/notifications/:channel", func(c *fiber.Ctx) error { return c.SendString("sent") })
+	app.Get("/healthz", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+	app.Get("/missing", func(*fiber.Ctx) error { return fiber.ErrNotFound })
+
+	for _, path := range []string{"/notifications/email", "/healthz", "/missing"} {
+		method := fiber.MethodGet
+		if strings.HasPrefix(path, "/notifications") {
+			method = fiber.MethodPost
+		}
+		// The Host header of the client is not recorded.
+		resp, err := app.Test(httptest.NewRequest(method, "http://attacker.example"+path, strings.NewReader("body")))
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	var scope *metricdata.ScopeMetrics
+	for i, sm := range rm.ScopeMetrics {
+		if sm.Scope.Name == fiberInstrumentationName {
+			scope = &rm.ScopeMetrics[i]
+		}
+	}
+	if scope == nil {
+		t.Fatalf("no metrics of scope %s", fiberInstrumentationName)
+	}
+	if scope.Scope.Version != otelcontrib.Version() {
+		t.Errorf("scope version = %q, want %q", scope.Scope.Version, otelcontrib.Version())
+	}
+
+	duration, _ := findMetric(rm, "http.server.duration").Data.(metricdata.Histogram[float64])
+	got := make(map[string]attribute.Set)
+	for _, point := range duration.DataPoints {
+		route, _ := point.Attributes.Value("http.route")
+		got[route.AsString()] = point.Attributes
+	}
+	want := map[string]int64{"/notifications/:channel": fiber.StatusOK, "/missing": fiber.StatusNotFound}
+	if len(got) != len(want) {
+		t.Fatalf("routes = %v, want %v", got, want)
+	}
+	for route, status := range want {
+		attrs := got[route]
+		for key, value := range map[attribute.Key]string{
+			"url.scheme":               "http",
+			"server.address":           "notification.local",
+			"network.protocol.name":    "http",
+			"network.protocol.version": "1.1",
+		} {
+			if v, _ := attrs.Value(key); v.AsString() != value {
+				t.Errorf("%s %s = %q, want %q", route, key, v.AsString(), value)
+			}
+		}
+		if v, _ := attrs.Value("http.response.status_code"); v.AsInt64() != status {
+			t.Errorf("%s status = %d, want %d", route, v.AsInt64(), status)
+		}
+	}
+
+	for _, name := range []string{"http.server.request.size", "http.server.response.size", "http.server.active_requests"} {
+		if findMetric(rm, name).Name == "" {
+			t.Errorf("%s not recorded", name)
+		}
+	}
+}
@@ -0,0 +1,501 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Environment variables configuring ExporterFile. Values set with
+// WithFileExporter take precedence.
+const (
+	envFileDirectory        = "OTEL_EXPORTER_FILE_DIRECTORY"
+	envFileMaxSize          = "OTEL_EXPORTER_FILE_MAX_SIZE"
+	envFileRotationInterval = "OTEL_EXPORTER_FILE_ROTATION_INTERVAL"
+	envFileMaxBackups       = "OTEL_EXPORTER_FILE_MAX_BACKUPS"
+	envFileCompress         = "OTEL_EXPORTER_FILE_COMPRESS"
+)
+
+const (
+	defaultFileDirectory        = "telemetry"
+	defaultFileMaxSize          = 100 << 20
+	defaultFileRotationInterval = 24 * time.Hour
+	defaultFileMaxBackups       = 7
+)
+
+// FileExporterConfig configures ExporterFile, which appends one OTLP/JSON
+// line per export to traces.jsonl, metrics.jsonl and logs.jsonl.
+type FileExporterConfig struct {
+	// Directory holds the files, "telemetry" by default.
+	Directory string
+	// MaxSize is the size in bytes after which a file is rotated, 100 MiB
+	// by default.
+	MaxSize int64
+	// RotationInterval is the age after which a file is rotated, 24 hours
+	// by default.
+	RotationInterval time.Duration
+	// MaxBackups is the number of rotated files kept per signal, 7 by
+	// default.
+	MaxBackups int
+	// DisableCompression keeps rotated files as plain JSONL instead of
+	// gzipping them.
+	DisableCompression bool
+}
+
+// fileExporterConfig completes the fields of cfg left unset from the
+// environment and the defaults.
+func fileExporterConfig(cfg FileExporterConfig) (FileExporterConfig, error) {
+	if cfg.Directory == "" {
+		cfg.Directory = os.Getenv(envFileDirectory)
+	}
+	if cfg.Directory == "" {
+		cfg.Directory = defaultFileDirectory
+	}
+
+	if value := os.Getenv(envFileMaxSize); cfg.MaxSize == 0 && value != "" {
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("telemetry: invalid %s value %q: %w", envFileMaxSize, value, err)
+		}
+		cfg.MaxSize = size
+	}
+	if cfg.MaxSize == 0 {
+		cfg.MaxSize = defaultFileMaxSize
+	}
+
+	if value := os.Getenv(envFileRotationInterval); cfg.RotationInterval == 0 && value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return cfg, fmt.Errorf("telemetry: invalid %s value %q: %w", envFileRotationInterval, value, err)
+		}
+		cfg.RotationInterval = interval
+	}
+	if cfg.RotationInterval == 0 {
+		cfg.RotationInterval = defaultFileRotationInterval
+	}
+
+	if value := os.Getenv(envFileMaxBackups); cfg.MaxBackups == 0 && value != "" {
+		backups, err := strconv.Atoi(value)
+		if err != nil {
+			return cfg, fmt.Errorf("telemetry: invalid %s value %q: %w", envFileMaxBackups, value, err)
+		}
+		cfg.MaxBackups = backups
+	}
+	if cfg.MaxBackups == 0 {
+		cfg.MaxBackups = defaultFileMaxBackups
+	}
+
+	if value := os.Getenv(envFileCompress); !cfg.DisableCompression && value != "" {
+		compress, err := strconv.ParseBool(value)
+		if err != nil {
+			return cfg, fmt.Errorf("telemetry: invalid %s value %q: %w", envFileCompress, value, err)
+		}
+		cfg.DisableCompression = !compress
+	}
+	return cfg, nil
+}
+
+func openSignalFile(cfg FileExporterConfig, signal string) (*rotatingFile, error) {
+	cfg, err := fileExporterConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newRotatingFile(
+		filepath.Join(cfg.Directory, signal+".jsonl"),
+		cfg.MaxSize,
+		cfg.RotationInterval,
+		cfg.MaxBackups,
+		!cfg.DisableCompression,
+	)
+}
+
+// writeOTLPJSON appends msg to file as one OTLP/JSON line.
+func writeOTLPJSON(file *rotatingFile, msg proto.Message) error {
+	line, err := marshalOTLPJSON(msg)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(line)
+	return err
+}
+
+// newFileTraceExporter returns a span exporter writing to traces.jsonl. The
+// OTLP trace exporter does the conversion to protobuf.
+func newFileTraceExporter(ctx context.Context, cfg FileExporterConfig) (trace.SpanExporter, error) {
+	file, err := openSignalFile(cfg, "traces")
+	if err != nil {
+		return nil, err
+	}
+	return otlptrace.New(ctx, &fileTraceClient{file: file})
+}
+
+type fileTraceClient struct {
+	file *rotatingFile
+}
+
+func (c *fileTraceClient) Start(context.Context) error { return nil }
+
+func (c *fileTraceClient) Stop(context.Context) error {
+	return c.file.Close()
+}
+
+func (c *fileTraceClient) UploadTraces(_ context.Context, spans []*tracepb.ResourceSpans) error {
+	return writeOTLPJSON(c.file, &tracepb.TracesData{ResourceSpans: spans})
+}
+
+// newFileMetricExporter returns a metric exporter writing to metrics.jsonl.
+func newFileMetricExporter(cfg FileExporterConfig) (metric.Exporter, error) {
+	file, err := openSignalFile(cfg, "metrics")
+	if err != nil {
+		return nil, err
+	}
+	return &fileMetricExporter{file: file}, nil
+}
+
+type fileMetricExporter struct {
+	file *rotatingFile
+}
+
+func (e *fileMetricExporter) Temporality(kind metric.InstrumentKind) metricdata.Temporality {
+	return metric.DefaultTemporalitySelector(kind)
+}
+
+func (e *fileMetricExporter) Aggregation(kind metric.InstrumentKind) metric.Aggregation {
+	return metric.DefaultAggregationSelector(kind)
+}
+
+func (e *fileMetricExporter) Export(_ context.Context, rm *metricdata.ResourceMetrics) error {
+	return writeOTLPJSON(e.file, &metricspb.MetricsData{
+		ResourceMetrics: []*metricspb.ResourceMetrics{resourceMetricsProto(rm)},
+	})
+}
+
+func (e *fileMetricExporter) ForceFlush(context.Context) error {
+	return e.file.Sync()
+}
+
+func (e *fileMetricExporter) Shutdown(context.Context) error {
+	return e.file.Close()
+}
+
+// newFileLogExporter returns a log exporter writing to logs.jsonl.
+func newFileLogExporter(cfg FileExporterConfig) (log.Exporter, error) {
+	file, err := openSignalFile(cfg, "logs")
+	if err != nil {
+		return nil, err
+	}
+	return &fileLogExporter{file: file}, nil
+}
+
+type fileLogExporter struct {
+	file *rotatingFile
+}
+
+func (e *fileLogExporter) Export(_ context.Context, records []log.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return writeOTLPJSON(e.file, &logspb.LogsData{ResourceLogs: resourceLogsProto(records)})
+}
+
+func (e *fileLogExporter) ForceFlush(context.Context) error {
+	return e.file.Sync()
+}
+
+func (e *fileLogExporter) Shutdown(context.Context) error {
+	return e.file.Close()
+}
+
+// resourceLogsProto groups records by resource and instrumentation scope,
+// keeping their order.
+func resourceLogsProto(records []log.Record) []*logspb.ResourceLogs {
+	var out []*logspb.ResourceLogs
+	resources := make(map[attribute.Distinct]*logspb.ResourceLogs)
+	scopes := make(map[attribute.Distinct]map[instrumentation.Scope]*logspb.ScopeLogs)
+
+	for i := range records {
+		record := &records[i]
+
+		res := record.Resource()
+		resKey := res.Equivalent()
+		rl, ok := resources[resKey]
+		if !ok {
+			rl = &logspb.ResourceLogs{Resource: resourceProto(&res), SchemaUrl: res.SchemaURL()}
+			resources[resKey] = rl
+			scopes[resKey] = make(map[instrumentation.Scope]*logspb.ScopeLogs)
+			out = append(out, rl)
+		}
+
+		scope := record.InstrumentationScope()
+		sl, ok := scopes[resKey][scope]
+		if !ok {
+			sl = &logspb.ScopeLogs{Scope: scopeProto(scope), SchemaUrl: scope.SchemaURL}
+			scopes[resKey][scope] = sl
+			rl.ScopeLogs = append(rl.ScopeLogs, sl)
+		}
+
+		sl.LogRecords = append(sl.LogRecords, logRecordProto(record))
+	}
+	return out
+}
+
+func logRecordProto(record *log.Record) *logspb.LogRecord {
+	var attrs []*commonpb.KeyValue
+	record.WalkAttributes(func(kv otellog.KeyValue) bool {
+		attrs = append(attrs, &commonpb.KeyValue{Key: kv.Key, Value: logValueProto(kv.Value)})
+		return true
+	})
+
+	pb := &logspb.LogRecord{
+		TimeUnixNano:           unixNano(record.Timestamp()),
+		ObservedTimeUnixNano:   unixNano(record.ObservedTimestamp()),
+		SeverityNumber:         logspb.SeverityNumber(record.Severity()),
+		SeverityText:           record.SeverityText(),
+		Attributes:             attrs,
+		DroppedAttributesCount: uint32(record.DroppedAttributes()),
+		Flags:                  uint32(record.TraceFlags()),
+		EventName:              record.EventName(),
+	}
+	if body := record.Body(); !body.Empty() {
+		pb.Body = logValueProto(body)
+	}
+	if traceID := record.TraceID(); traceID.IsValid() {
+		pb.TraceId = traceID[:]
+	}
+	if spanID := record.SpanID(); spanID.IsValid() {
+		pb.SpanId = spanID[:]
+	}
+	return pb
+}
+
+func resourceMetricsProto(rm *metricdata.ResourceMetrics) *metricspb.ResourceMetrics {
+	out := &metricspb.ResourceMetrics{
+		Resource:  resourceProto(rm.Resource),
+		SchemaUrl: rm.Resource.SchemaURL(),
+	}
+	for _, sm := range rm.ScopeMetrics {
+		scope := &metricspb.ScopeMetrics{
+			Scope:     scopeProto(sm.Scope),
+			SchemaUrl: sm.Scope.SchemaURL,
+		}
+		for _, m := range sm.Metrics {
+			if pb := metricProto(m); pb != nil {
+				scope.Metrics = append(scope.Metrics, pb)
+			}
+		}
+		out.ScopeMetrics = append(out.ScopeMetrics, scope)
+	}
+	return out
+}
+
+// metricProto converts m, returning nil for unknown aggregations.
+func metricProto(m metricdata.Metrics) *metricspb.Metric {
+	pb := &metricspb.Metric{
+		Name:        m.Name,
+		Description: m.Description,
+		Unit:        m.Unit,
+	}
+
+	switch data := m.Data.(type) {
+	case metricdata.Gauge[int64]:
+		pb.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: numberPointsProto(data.DataPoints)}}
+	case metricdata.Gauge[float64]:
+		pb.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: numberPointsProto(data.DataPoints)}}
+	case metricdata.Sum[int64]:
+		pb.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			DataPoints:             numberPointsProto(data.DataPoints),
+			AggregationTemporality: temporalityProto(data.Temporality),
+			IsMonotonic:            data.IsMonotonic,
+		}}
+	case metricdata.Sum[float64]:
+		pb.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			DataPoints:             numberPointsProto(data.DataPoints),
+			AggregationTemporality: temporalityProto(data.Temporality),
+			IsMonotonic:            data.IsMonotonic,
+		}}
+	case metricdata.Histogram[int64]:
+		pb.Data = &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+			DataPoints:             histogramPointsProto(data.DataPoints),
+			AggregationTemporality: temporalityProto(data.Temporality),
+		}}
+	case metricdata.Histogram[float64]:
+		pb.Data = &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+			DataPoints:             histogramPointsProto(data.DataPoints),
+			AggregationTemporality: temporalityProto(data.Temporality),
+		}}
+	case metricdata.ExponentialHistogram[int64]:
+		pb.Data = &metricspb.Metric_ExponentialHistogram{ExponentialHistogram: &metricspb.ExponentialHistogram{
+			DataPoints:             exponentialPointsProto(data.DataPoints),
+			AggregationTemporality: temporalityProto(data.Temporality),
+		}}
+	case metricdata.ExponentialHistogram[float64]:
+		pb.Data = &metricspb.Metric_ExponentialHistogram{ExponentialHistogram: &metricspb.ExponentialHistogram{
+			DataPoints:             exponentialPointsProto(data.DataPoints),
+			AggregationTemporality: temporalityProto(data.Temporality),
+		}}
+	case metricdata.Summary:
+		pb.Data = &metricspb.Metric_Summary{Summary: &metricspb.Summary{DataPoints: summaryPointsProto(data.DataPoints)}}
+	default:
+		return nil
+	}
+	return pb
+}
+
+func temporalityProto(t metricdata.Temporality) metricspb.AggregationTemporality {
+	switch t {
+	case metricdata.DeltaTemporality:
+		return metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+	case metricdata.CumulativeTemporality:
+		return metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+	default:
+		return metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_UNSPECIFIED
+	}
+}
+
+func numberPointsProto[N int64 | float64](points []metricdata.DataPoint[N]) []*metricspb.NumberDataPoint {
+	out := make([]*metricspb.NumberDataPoint, 0, len(points))
+	for _, p := range points {
+		pb := &metricspb.NumberDataPoint{
+			Attributes:        attributesProto(p.Attributes.ToSlice()),
+			StartTimeUnixNano: unixNano(p.StartTime),
+			TimeUnixNano:      unixNano(p.Time),
+			Exemplars:         exemplarsProto(p.Exemplars),
+		}
+		switch v := any(p.Value).(type) {
+		case int64:
+			pb.Value = &metricspb.NumberDataPoint_AsInt{AsInt: v}
+		case float64:
+			pb.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: v}
+		}
+		out = append(out, pb)
+	}
+	return out
+}
+
+func histogramPointsProto[N int64 | float64](points []metricdata.HistogramDataPoint[N]) []*metricspb.HistogramDataPoint {
+	out := make([]*metricspb.HistogramDataPoint, 0, len(points))
+	for _, p := range points {
+		sum := float64(p.Sum)
+		pb := &metricspb.HistogramDataPoint{
+			Attributes:        attributesProto(p.Attributes.ToSlice()),
+			StartTimeUnixNano: unixNano(p.StartTime),
+			TimeUnixNano:      unixNano(p.Time),
+			Count:             p.Count,
+			Sum:               &sum,
+			BucketCounts:      p.BucketCounts,
+			ExplicitBounds:    p.Bounds,
+			Exemplars:         exemplarsProto(p.Exemplars),
+			Min:               extremaProto(p.Min),
+			Max:               extremaProto(p.Max),
+		}
+		out = append(out, pb)
+	}
+	return out
+}
+
+func exponentialPointsProto[N int64 | float64](points []metricdata.ExponentialHistogramDataPoint[N]) []*metricspb.ExponentialHistogramDataPoint {
+	out := make([]*metricspb.ExponentialHistogramDataPoint, 0, len(points))
+	for _, p := range points {
+		sum := float64(p.Sum)
+		pb := &metricspb.ExponentialHistogramDataPoint{
+			Attributes:        attributesProto(p.Attributes.ToSlice()),
+			StartTimeUnixNano: unixNano(p.StartTime),
+			TimeUnixNano:      unixNano(p.Time),
+			Count:             p.Count,
+			Sum:               &sum,
+			Scale:             p.Scale,
+			ZeroCount:         p.ZeroCount,
+			ZeroThreshold:     p.ZeroThreshold,
+			Positive: &metricspb.ExponentialHistogramDataPoint_Buckets{
+				Offset:       p.PositiveBucket.Offset,
+				BucketCounts: p.PositiveBucket.Counts,
+			},
+			Negative: &metricspb.ExponentialHistogramDataPoint_Buckets{
+				Offset:       p.NegativeBucket.Offset,
+				BucketCounts: p.NegativeBucket.Counts,
+			},
+			Exemplars: exemplarsProto(p.Exemplars),
+			Min:       extremaProto(p.Min),
+			Max:       extremaProto(p.Max),
+		}
+		out = append(out, pb)
+	}
+	return out
+}
+
+func summaryPointsProto(points []metricdata.SummaryDataPoint) []*metricspb.SummaryDataPoint {
+	out := make([]*metricspb.SummaryDataPoint, 0, len(points))
+	for _, p := range points {
+		pb := &metricspb.SummaryDataPoint{
+			Attributes:        attributesProto(p.Attributes.ToSlice()),
+			StartTimeUnixNano: unixNano(p.StartTime),
+			TimeUnixNano:      unixNano(p.Time),
+			Count:             p.Count,
+			Sum:               p.Sum,
+		}
+		for _, q := range p.QuantileValues {
+			pb.QuantileValues = append(pb.QuantileValues, &metricspb.SummaryDataPoint_ValueAtQuantile{
+				Quantile: q.Quantile,
+				Value:    q.Value,
+			})
+		}
+		out = append(out, pb)
+	}
+	return out
+}
+
+func exemplarsProto[N int64 | float64](exemplars []metricdata.Exemplar[N]) []*metricspb.Exemplar {
+	if len(exemplars) == 0 {
+		return nil
+	}
+	out := make([]*metricspb.Exemplar, 0, len(exemplars))
+	for _, e := range exemplars {
+		pb := &metricspb.Exemplar{
+			FilteredAttributes: attributesProto(e.FilteredAttributes),
+			TimeUnixNano:       unixNano(e.Time),
+			SpanId:             e.SpanID,
+			TraceId:            e.TraceID,
+		}
+		switch v := any(e.Value).(type) {
+		case int64:
+			pb.Value = &metricspb.Exemplar_AsInt{AsInt: v}
+		case float64:
+			pb.Value = &metricspb.Exemplar_AsDouble{AsDouble: v}
+		}
+		out = append(out, pb)
+	}
+	return out
+}
+
+func extremaProto[N int64 | float64](e metricdata.Extrema[N]) *float64 {
+	v, ok := e.Value()
+	if !ok {
+		return nil
+	}
+	f := float64(v)
+	return &f
+}
+
+func unixNano(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
@@ -0,0 +1,105 @@
+package telemetry
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestFileExporter(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("OTEL_TRACES_SAMPLER", "always_on")
+
+	ctx := context.Background()
+	shutdown, err := SetupOTelSDK(ctx, "file-test",
+		WithTraceExporter(ExporterFile),
+		WithMetricExporter(ExporterFile),
+		WithLogExporter(ExporterFile),
+		WithFileExporter(FileExporterConfig{Directory: dir}),
+	)
+	if err != nil {
+		t.Fatalf("SetupOTelSDK: %v", err)
+	}
+
+	spanCtx, span := otel.Tracer("file-test").Start(ctx, "file-span")
+	var record otellog.Record
+	record.SetBody(otellog.StringValue("file-log"))
+	global.GetLoggerProvider().Logger("file-test").Emit(spanCtx, record)
+	span.End()
+
+	counter, err := otel.Meter("file-test").Int64Counter("file.counter")
+	if err != nil {
+		t.Fatalf("create counter: %v", err)
+	}
+	counter.Add(ctx, 3)
+
+	if err := shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	traceID := span.SpanContext().TraceID()
+
+	traces := &tracepb.TracesData{}
+	readJSONL(t, filepath.Join(dir, "traces.jsonl"), traces)
+	gotSpan := traces.GetResourceSpans()[0].GetScopeSpans()[0].GetSpans()[0]
+	if gotSpan.GetName() != "file-span" || [16]byte(gotSpan.GetTraceId()) != traceID {
+		t.Errorf("span = %s in trace %x, want file-span in trace %s", gotSpan.GetName(), gotSpan.GetTraceId(), traceID)
+	}
+
+	logs := &logspb.LogsData{}
+	readJSONL(t, filepath.Join(dir, "logs.jsonl"), logs)
+	gotLog := logs.GetResourceLogs()[0].GetScopeLogs()[0].GetLogRecords()[0]
+	if gotLog.GetBody().GetStringValue() != "file-log" || [16]byte(gotLog.GetTraceId()) != traceID {
+		t.Errorf("log = %q in trace %x, want file-log in trace %s", gotLog.GetBody().GetStringValue(), gotLog.GetTraceId(), traceID)
+	}
+
+	metrics := &metricspb.MetricsData{}
+	readJSONL(t, filepath.Join(dir, "metrics.jsonl"), metrics)
+	var found bool
+	for _, rm := range metrics.GetResourceMetrics() {
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				if m.GetName() == "file.counter" {
+					found = true
+					if got := m.GetSum().GetDataPoints()[0].GetAsInt(); got != 3 {
+						t.Errorf("file.counter = %d, want 3", got)
+					}
+				}
+			}
+		}
+	}
+	if !found {
+		t.Errorf("file.counter not written")
+	}
+}
+
+// readJSONL decodes every line of path into msg, merging the exports.
+func readJSONL(t *testing.T, path string, msg proto.Message) {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := msg.ProtoReflect().New().Interface()
+		unmarshalOTLPJSON(t, scanner.Bytes(), line)
+		proto.Merge(msg, line)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+}
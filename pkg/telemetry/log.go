@@ -0,0 +1,36 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/contrib/bridges/otelzap"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NewZapCore returns a zap core forwarding entries to the global OpenTelemetry
+// LoggerProvider. Entries carrying a ContextField are correlated with the
+// span found in that context.
+func NewZapCore(name string) zapcore.Core {
+	return otelzap.NewCore(name)
+}
+
+// ZapOption tees a logger into NewZapCore, applying the redaction rules
+// given to SetupOTelSDK to the wrapped core. Entries forwarded to NewZapCore
+// are redacted by the LoggerProvider, like any other log record, e.g.
+//
+//	zap.ReplaceGlobals(zap.L().WithOptions(telemetry.ZapOption("notification-server")))
+func ZapOption(name string) zap.Option {
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(
+			newRedactingCore(core, loadState().redactor),
+			NewZapCore(name),
+		)
+	})
+}
+
+// ContextField attaches ctx to a log entry. It is skipped by the regular zap
+// encoders and used by NewZapCore to fill in the trace and span IDs.
+func ContextField(ctx context.Context) zap.Field {
+	return zap.Field{Key: "context", Type: zapcore.SkipType, Interface: ctx}
+}
@@ -0,0 +1,38 @@
+package telemetry
+
+import (
+	"context"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Logger returns the global zap logger annotated with the trace_id, span_id
+// and trace_flags of the span in ctx and with the configured baggage members.
+// The context itself is attached as well, so entries forwarded to the
+// OpenTelemetry logs pipeline are correlated with the span.
+func Logger(ctx context.Context) *zap.Logger {
+	return zap.L().With(LogFields(ctx)...)
+}
+
+// LogFields returns the fields added by Logger.
+func LogFields(ctx context.Context) []zap.Field {
+	fields := []zap.Field{ContextField(ctx)}
+
+	spanCtx := oteltrace.SpanContextFromContext(ctx)
+	if spanCtx.IsValid() {
+		fields = append(fields,
+			zap.String("trace_id", spanCtx.TraceID().String()),
+			zap.String("span_id", spanCtx.SpanID().String()),
+			zap.String("trace_flags", spanCtx.TraceFlags().String()),
+		)
+	}
+
+	if promoter := loadState().loggerBaggage; promoter != nil {
+		promoter.members(ctx, func(key, value string) {
+			fields = append(fields, zap.String(baggageAttributePrefix+key, value))
+		})
+	}
+
+	return fields
+}
@@ -0,0 +1,58 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogger(t *testing.T) {
+	setupRuntime(t, WithLoggerBaggageKeys("tenant_id"))
+	core, logs := observer.New(zap.DebugLevel)
+	t.Cleanup(zap.ReplaceGlobals(zap.New(core)))
+
+	provider := sdktrace.NewTracerProvider()
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	ctx, span := provider.Tracer("logger-test").Start(context.Background(), "logged")
+	defer span.End()
+	member, _ := baggage.NewMember("tenant_id", "acme")
+	other, _ := baggage.NewMember("session", "s-1")
+	bag, _ := baggage.New(member, other)
+	ctx = baggage.ContextWithBaggage(ctx, bag)
+
+	Logger(ctx).Info("sent")
+	Logger(context.Background()).Info("untraced")
+
+	entries := logs.AllUntimed()
+	if len(entries) != 2 {
+		t.Fatalf("logged %d entries, want 2", len(entries))
+	}
+	spanCtx := span.SpanContext()
+	want := map[string]interface{}{
+		"trace_id":          spanCtx.TraceID().String(),
+		"span_id":           spanCtx.SpanID().String(),
+		"trace_flags":       spanCtx.TraceFlags().String(),
+		"baggage.tenant_id": "acme",
+	}
+	got := entries[0].ContextMap()
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s = %v, want %v", key, got[key], value)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+	if untraced := entries[1].ContextMap(); len(untraced) != 0 {
+		t.Errorf("untraced fields = %v, want none", untraced)
+	}
+
+	fields := LogFields(ctx)
+	if fields[0].Key != "context" || fields[0].Interface != ctx {
+		t.Errorf("first field = %v, want the context", fields[0])
+	}
+}
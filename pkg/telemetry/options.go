@@ -0,0 +1,310 @@
+package telemetry
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/exemplar"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+)
+
+// Production defaults, matching the OpenTelemetry SDK defaults.
+const (
+	defaultBatchTimeout       = 5 * time.Second
+	defaultExportTimeout      = 30 * time.Second
+	defaultMaxQueueSize       = 2048
+	defaultMaxExportBatchSize = 512
+	defaultMetricInterval     = 60 * time.Second
+	defaultMetricTimeout      = 30 * time.Second
+)
+
+type config struct {
+	traceExporter  Exporter
+	metricExporter Exporter
+	logExporter    Exporter
+	fileExporter   FileExporterConfig
+
+	sampler            trace.Sampler
+	samplingRules      []SamplingRule
+	batchTimeout       time.Duration
+	exportTimeout      time.Duration
+	maxQueueSize       int
+	maxExportBatchSize int
+	spanProcessors     []trace.SpanProcessor
+	tailSampling       *TailSamplingConfig
+	redactionRules     []RedactionRule
+	hashKey            []byte
+
+	metricInterval time.Duration
+	metricTimeout  time.Duration
+	metricReaders  []metric.Reader
+	prometheus     bool
+	exemplarFilter exemplar.Filter
+	metricGroups   []MetricGroup
+
+	logProcessors     []log.Processor
+	loggerBaggageKeys []string
+	baggage           *BaggageConfig
+
+	serviceVersion     string
+	serviceInstanceID  string
+	resourceAttributes []attribute.KeyValue
+	propagators        []propagation.TextMapPropagator
+	propagatorNames    []string
+
+	errorLogBurst    int
+	errorLogInterval time.Duration
+	logLevel         *zap.AtomicLevel
+}
+
+func newConfig(opts ...Option) *config {
+	cfg := &config{
+		batchTimeout:       defaultBatchTimeout,
+		exportTimeout:      defaultExportTimeout,
+		maxQueueSize:       defaultMaxQueueSize,
+		maxExportBatchSize: defaultMaxExportBatchSize,
+		metricInterval:     defaultMetricInterval,
+		metricTimeout:      defaultMetricTimeout,
+		errorLogBurst:      defaultErrorLogBurst,
+		errorLogInterval:   defaultErrorLogInterval,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// Option configures SetupOTelSDK.
+type Option func(*config)
+
+// WithTraceExporter overrides the trace exporter selected from OTEL_TRACES_EXPORTER.
+func WithTraceExporter(exporter Exporter) Option {
+	return func(c *config) {
+		c.traceExporter = exporter
+	}
+}
+
+// WithMetricExporter overrides the metric exporter selected from OTEL_METRICS_EXPORTER.
+func WithMetricExporter(exporter Exporter) Option {
+	return func(c *config) {
+		c.metricExporter = exporter
+	}
+}
+
+// WithLogExporter overrides the log exporter selected from OTEL_LOGS_EXPORTER.
+func WithLogExporter(exporter Exporter) Option {
+	return func(c *config) {
+		c.logExporter = exporter
+	}
+}
+
+// WithFileExporter configures the files written by ExporterFile.
+func WithFileExporter(fileCfg FileExporterConfig) Option {
+	return func(c *config) {
+		c.fileExporter = fileCfg
+	}
+}
+
+// WithSampler sets the sampler of the tracer provider, overriding OTEL_TRACES_SAMPLER.
+func WithSampler(sampler trace.Sampler) Option {
+	return func(c *config) {
+		c.sampler = sampler
+	}
+}
+
+// WithSamplingRules adds rules evaluated before the configured sampler,
+// which becomes the fallback for spans matching no rule.
+func WithSamplingRules(rules ...SamplingRule) Option {
+	return func(c *config) {
+		c.samplingRules = append(c.samplingRules, rules...)
+	}
+}
+
+// WithBatchTimeout sets the maximum delay before a batch of spans is exported.
+func WithBatchTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.batchTimeout = timeout
+	}
+}
+
+// WithExportTimeout sets how long a single span export may run.
+func WithExportTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.exportTimeout = timeout
+	}
+}
+
+// WithMaxQueueSize sets the maximum number of spans buffered before they are dropped.
+func WithMaxQueueSize(size int) Option {
+	return func(c *config) {
+		c.maxQueueSize = size
+	}
+}
+
+// WithMaxExportBatchSize sets the maximum number of spans sent in one export.
+func WithMaxExportBatchSize(size int) Option {
+	return func(c *config) {
+		c.maxExportBatchSize = size
+	}
+}
+
+// WithTailSampling routes exported spans through a tail sampling processor.
+// Its decisions are recorded by the meter provider set up by SetupOTelSDK,
+// in place of tailCfg.MeterProvider.
+func WithTailSampling(tailCfg TailSamplingConfig) Option {
+	return func(c *config) {
+		c.tailSampling = &tailCfg
+	}
+}
+
+// WithRedactionRules redacts matching span attributes, span event
+// attributes, log record attributes and zap fields before they are
+// exported, recorded by the registered processors or written.
+func WithRedactionRules(rules ...RedactionRule) Option {
+	return func(c *config) {
+		c.redactionRules = append(c.redactionRules, rules...)
+	}
+}
+
+// WithHashKey keys the HMAC computed by HashValue. Processes sharing the key
+// hash equal values alike; by default each process uses a random key.
+func WithHashKey(key []byte) Option {
+	return func(c *config) {
+		c.hashKey = key
+	}
+}
+
+// WithSpanProcessor registers an additional span processor.
+func WithSpanProcessor(processor trace.SpanProcessor) Option {
+	return func(c *config) {
+		c.spanProcessors = append(c.spanProcessors, processor)
+	}
+}
+
+// WithMetricInterval sets the interval between periodic metric exports.
+func WithMetricInterval(interval time.Duration) Option {
+	return func(c *config) {
+		c.metricInterval = interval
+	}
+}
+
+// WithMetricTimeout sets how long a single metric export may run.
+func WithMetricTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.metricTimeout = timeout
+	}
+}
+
+// WithExemplarFilter selects the measurements kept as exemplars, overriding
+// OTEL_METRICS_EXEMPLAR_FILTER. By default measurements recorded within a
+// sampled span are offered.
+func WithExemplarFilter(filter exemplar.Filter) Option {
+	return func(c *config) {
+		c.exemplarFilter = filter
+	}
+}
+
+// WithMetricGroups enables the given runtime, GC, process and host metric
+// groups, overriding OTEL_GO_METRIC_GROUPS.
+func WithMetricGroups(groups ...MetricGroup) Option {
+	return func(c *config) {
+		c.metricGroups = append([]MetricGroup{}, groups...)
+	}
+}
+
+// WithMetricReader registers an additional metric reader.
+func WithMetricReader(reader metric.Reader) Option {
+	return func(c *config) {
+		c.metricReaders = append(c.metricReaders, reader)
+	}
+}
+
+// WithLogProcessor registers an additional log record processor.
+func WithLogProcessor(processor log.Processor) Option {
+	return func(c *config) {
+		c.logProcessors = append(c.logProcessors, processor)
+	}
+}
+
+// WithLoggerBaggageKeys selects the baggage members added to Logger fields.
+func WithLoggerBaggageKeys(keys ...string) Option {
+	return func(c *config) {
+		c.loggerBaggageKeys = append(c.loggerBaggageKeys, keys...)
+	}
+}
+
+// WithPrometheus enables the Prometheus scrape endpoint served by
+// PrometheusHandler, next to the configured metric exporter.
+func WithPrometheus() Option {
+	return func(c *config) {
+		c.prometheus = true
+	}
+}
+
+// WithBaggagePromotion copies the allowed baggage members onto every span,
+// log record and Logger entry.
+func WithBaggagePromotion(baggageCfg BaggageConfig) Option {
+	return func(c *config) {
+		c.baggage = &baggageCfg
+	}
+}
+
+// WithServiceVersion sets service.version, which defaults to the module version
+// embedded in the binary.
+func WithServiceVersion(version string) Option {
+	return func(c *config) {
+		c.serviceVersion = version
+	}
+}
+
+// WithServiceInstanceID sets service.instance.id, which defaults to a random UUID.
+func WithServiceInstanceID(id string) Option {
+	return func(c *config) {
+		c.serviceInstanceID = id
+	}
+}
+
+// WithResourceAttributes adds attributes describing the process to all telemetry.
+func WithResourceAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.resourceAttributes = append(c.resourceAttributes, attrs...)
+	}
+}
+
+// WithPropagators replaces the propagators selected from OTEL_PROPAGATORS.
+func WithPropagators(propagators ...propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithPropagatorNames selects propagators by their OTEL_PROPAGATORS name,
+// e.g. "b3", "tracecontext", "baggage", overriding the environment.
+func WithPropagatorNames(names ...string) Option {
+	return func(c *config) {
+		c.propagatorNames = names
+	}
+}
+
+// WithErrorLogRate limits the errors of the SDK and its exporters logged
+// through zap to burst per interval, by default 10 per minute. A burst or
+// interval of zero logs every error.
+func WithErrorLogRate(burst int, interval time.Duration) Option {
+	return func(c *config) {
+		c.errorLogBurst = burst
+		c.errorLogInterval = interval
+	}
+}
+
+// WithLogLevel lets UpdateRuntimeConfig, and so AdminHandler and
+// ReloadOnSIGHUP, change level, typically the Level of the zap.Config the
+// global logger was built from.
+func WithLogLevel(level zap.AtomicLevel) Option {
+	return func(c *config) {
+		c.logLevel = &level
+	}
+}
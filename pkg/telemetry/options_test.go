@@ -0,0 +1,126 @@
+package telemetry
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestNewConfig(t *testing.T) {
+	sampler := trace.AlwaysSample()
+	defaults := func() *config {
+		return &config{
+			batchTimeout:       defaultBatchTimeout,
+			exportTimeout:      defaultExportTimeout,
+			maxQueueSize:       defaultMaxQueueSize,
+			maxExportBatchSize: defaultMaxExportBatchSize,
+			metricInterval:     defaultMetricInterval,
+			metricTimeout:      defaultMetricTimeout,
+			errorLogBurst:      defaultErrorLogBurst,
+			errorLogInterval:   defaultErrorLogInterval,
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+		want func(*config)
+	}{
+		{
+			name: "defaults",
+			want: func(*config) {},
+		},
+		{
+			name: "exporters",
+			opts: []Option{
+				WithTraceExporter(ExporterStdout),
+				WithMetricExporter(ExporterOTLPGRPC),
+				WithLogExporter(ExporterNone),
+			},
+			want: func(c *config) {
+				c.traceExporter = ExporterStdout
+				c.metricExporter = ExporterOTLPGRPC
+				c.logExporter = ExporterNone
+			},
+		},
+		{
+			name: "batching",
+			opts: []Option{
+				WithSampler(sampler),
+				WithBatchTimeout(time.Second),
+				WithExportTimeout(2 * time.Second),
+				WithMaxQueueSize(100),
+				WithMaxExportBatchSize(10),
+			},
+			want: func(c *config) {
+				c.sampler = sampler
+				c.batchTimeout = time.Second
+				c.exportTimeout = 2 * time.Second
+				c.maxQueueSize = 100
+				c.maxExportBatchSize = 10
+			},
+		},
+		{
+			name: "metrics",
+			opts: []Option{
+				WithMetricInterval(time.Second),
+				WithMetricTimeout(2 * time.Second),
+				WithMetricGroups(MetricGroupRuntime),
+				WithMetricGroups(MetricGroupGC, MetricGroupProcess),
+				WithPrometheus(),
+			},
+			want: func(c *config) {
+				c.metricInterval = time.Second
+				c.metricTimeout = 2 * time.Second
+				c.metricGroups = []MetricGroup{MetricGroupGC, MetricGroupProcess}
+				c.prometheus = true
+			},
+		},
+		{
+			name: "repeated options append",
+			opts: []Option{
+				WithResourceAttributes(attribute.String("team", "notification")),
+				WithResourceAttributes(attribute.String("region", "eu")),
+				WithLoggerBaggageKeys("user_id"),
+				WithLoggerBaggageKeys("tenant_id"),
+			},
+			want: func(c *config) {
+				c.resourceAttributes = []attribute.KeyValue{
+					attribute.String("team", "notification"),
+					attribute.String("region", "eu"),
+				}
+				c.loggerBaggageKeys = []string{"user_id", "tenant_id"}
+			},
+		},
+		{
+			name: "service and propagation",
+			opts: []Option{
+				WithServiceVersion("v1.2.3"),
+				WithServiceInstanceID("instance-1"),
+				WithPropagatorNames("b3"),
+				WithPropagatorNames("tracecontext", "baggage"),
+				WithErrorLogRate(0, 0),
+			},
+			want: func(c *config) {
+				c.serviceVersion = "v1.2.3"
+				c.serviceInstanceID = "instance-1"
+				c.propagatorNames = []string{"tracecontext", "baggage"}
+				c.errorLogBurst = 0
+				c.errorLogInterval = 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := defaults()
+			tt.want(want)
+			if got := newConfig(tt.opts...); !reflect.DeepEqual(got, want) {
+				t.Errorf("newConfig() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
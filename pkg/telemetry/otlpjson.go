@@ -0,0 +1,169 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// marshalOTLPJSON encodes msg as a single line of OTLP/JSON. Unlike plain
+// protojson, trace and span IDs are hex encoded as the OTLP specification
+// requires, so the output can be replayed to a collector.
+func marshalOTLPJSON(msg proto.Message) ([]byte, error) {
+	raw, err := protojson.MarshalOptions{UseEnumNumbers: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	hexIDs(doc)
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// hexIDs re-encodes the base64 trace and span IDs found anywhere in doc.
+func hexIDs(doc any) {
+	switch v := doc.(type) {
+	case map[string]any:
+		for key, value := range v {
+			switch key {
+			case "traceId", "spanId", "parentSpanId":
+				if s, ok := value.(string); ok {
+					if id, err := base64.StdEncoding.DecodeString(s); err == nil {
+						v[key] = hex.EncodeToString(id)
+					}
+				}
+			default:
+				hexIDs(value)
+			}
+		}
+	case []any:
+		for _, value := range v {
+			hexIDs(value)
+		}
+	}
+}
+
+func resourceProto(res *resource.Resource) *resourcepb.Resource {
+	if res == nil {
+		return &resourcepb.Resource{}
+	}
+	return &resourcepb.Resource{Attributes: attributesProto(res.Attributes())}
+}
+
+func scopeProto(scope instrumentation.Scope) *commonpb.InstrumentationScope {
+	return &commonpb.InstrumentationScope{
+		Name:       scope.Name,
+		Version:    scope.Version,
+		Attributes: attributesProto(scope.Attributes.ToSlice()),
+	}
+}
+
+func attributesProto(attrs []attribute.KeyValue) []*commonpb.KeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	out := make([]*commonpb.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		out = append(out, &commonpb.KeyValue{
+			Key:   string(attr.Key),
+			Value: attributeValueProto(attr.Value),
+		})
+	}
+	return out
+}
+
+func attributeValueProto(v attribute.Value) *commonpb.AnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case attribute.INT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case attribute.FLOAT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	case attribute.STRING:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.AsString()}}
+	case attribute.BOOLSLICE:
+		return arrayProto(v.AsBoolSlice(), func(b bool) *commonpb.AnyValue {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: b}}
+		})
+	case attribute.INT64SLICE:
+		return arrayProto(v.AsInt64Slice(), func(i int64) *commonpb.AnyValue {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: i}}
+		})
+	case attribute.FLOAT64SLICE:
+		return arrayProto(v.AsFloat64Slice(), func(f float64) *commonpb.AnyValue {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: f}}
+		})
+	case attribute.STRINGSLICE:
+		return arrayProto(v.AsStringSlice(), func(s string) *commonpb.AnyValue {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+		})
+	default:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.Emit()}}
+	}
+}
+
+func arrayProto[T any](values []T, fn func(T) *commonpb.AnyValue) *commonpb.AnyValue {
+	array := &commonpb.ArrayValue{Values: make([]*commonpb.AnyValue, 0, len(values))}
+	for _, value := range values {
+		array.Values = append(array.Values, fn(value))
+	}
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: array}}
+}
+
+func logValueProto(v otellog.Value) *commonpb.AnyValue {
+	switch v.Kind() {
+	case otellog.KindBool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case otellog.KindInt64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case otellog.KindFloat64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	case otellog.KindString:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.AsString()}}
+	case otellog.KindBytes:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: v.AsBytes()}}
+	case otellog.KindSlice:
+		return arrayProto(v.AsSlice(), logValueProto)
+	case otellog.KindMap:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{
+			KvlistValue: &commonpb.KeyValueList{Values: logAttributesProto(v.AsMap())},
+		}}
+	default:
+		return nil
+	}
+}
+
+func logAttributesProto(attrs []otellog.KeyValue) []*commonpb.KeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	out := make([]*commonpb.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		out = append(out, &commonpb.KeyValue{
+			Key:   attr.Key,
+			Value: logValueProto(attr.Value),
+		})
+	}
+	return out
+}
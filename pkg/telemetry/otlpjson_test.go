@@ -0,0 +1,155 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestHexIDs(t *testing.T) {
+	id := []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+	encoded := base64.StdEncoding.EncodeToString(id)
+
+	tests := []struct {
+		name string
+		doc  any
+		want any
+	}{
+		{
+			name: "top level",
+			doc:  map[string]any{"traceId": encoded, "spanId": encoded, "parentSpanId": encoded},
+			want: map[string]any{"traceId": "0123456789abcdef", "spanId": "0123456789abcdef", "parentSpanId": "0123456789abcdef"},
+		},
+		{
+			name: "nested in arrays",
+			doc:  map[string]any{"resourceSpans": []any{map[string]any{"spans": []any{map[string]any{"spanId": encoded}}}}},
+			want: map[string]any{"resourceSpans": []any{map[string]any{"spans": []any{map[string]any{"spanId": "0123456789abcdef"}}}}},
+		},
+		{
+			name: "other keys kept",
+			doc:  map[string]any{"name": encoded, "attributes": []any{map[string]any{"key": "traceId"}}},
+			want: map[string]any{"name": encoded, "attributes": []any{map[string]any{"key": "traceId"}}},
+		},
+		{
+			name: "not base64",
+			doc:  map[string]any{"traceId": "not base64!", "spanId": 42},
+			want: map[string]any{"traceId": "not base64!", "spanId": 42},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hexIDs(tt.doc)
+			if !reflect.DeepEqual(tt.doc, tt.want) {
+				t.Errorf("hexIDs = %v, want %v", tt.doc, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalOTLPJSONRoundTrip(t *testing.T) {
+	want := &tracepb.TracesData{ResourceSpans: []*tracepb.ResourceSpans{{
+		Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{{
+			Key:   "service.name",
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "otlpjson-test"}},
+		}}},
+		ScopeSpans: []*tracepb.ScopeSpans{{
+			Spans: []*tracepb.Span{{
+				TraceId:           bytes.Repeat([]byte{0xab}, 16),
+				SpanId:            bytes.Repeat([]byte{0xcd}, 8),
+				ParentSpanId:      bytes.Repeat([]byte{0xef}, 8),
+				Name:              "round-trip",
+				Kind:              tracepb.Span_SPAN_KIND_SERVER,
+				StartTimeUnixNano: 1,
+				EndTimeUnixNano:   2,
+			}},
+		}},
+	}}}
+
+	line, err := marshalOTLPJSON(want)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if bytes.IndexByte(line, '\n') != len(line)-1 {
+		t.Fatalf("marshal = %q, want a single line", line)
+	}
+
+	var doc struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID      string `json:"traceId"`
+					SpanID       string `json:"spanId"`
+					ParentSpanID string `json:"parentSpanId"`
+					Kind         int    `json:"kind"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal(line, &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	span := doc.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if span.TraceID != "abababababababababababababababab" || span.SpanID != "cdcdcdcdcdcdcdcd" || span.ParentSpanID != "efefefefefefefef" {
+		t.Errorf("IDs = %s/%s/%s, want hex", span.TraceID, span.SpanID, span.ParentSpanID)
+	}
+	if span.Kind != int(tracepb.Span_SPAN_KIND_SERVER) {
+		t.Errorf("kind = %d, want enum number %d", span.Kind, tracepb.Span_SPAN_KIND_SERVER)
+	}
+
+	got := &tracepb.TracesData{}
+	unmarshalOTLPJSON(t, line, got)
+	if !proto.Equal(got, want) {
+		t.Errorf("round trip = %v, want %v", got, want)
+	}
+}
+
+// unmarshalOTLPJSON decodes a line written by marshalOTLPJSON into msg,
+// turning the hex trace and span IDs back into the base64 protojson reads.
+func unmarshalOTLPJSON(t *testing.T, line []byte, msg proto.Message) {
+	t.Helper()
+
+	var doc any
+	if err := json.Unmarshal(line, &doc); err != nil {
+		t.Fatalf("decode %q: %v", line, err)
+	}
+	base64IDs(t, doc)
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if err := protojson.Unmarshal(raw, msg); err != nil {
+		t.Fatalf("protojson: %v", err)
+	}
+}
+
+func base64IDs(t *testing.T, doc any) {
+	switch v := doc.(type) {
+	case map[string]any:
+		for key, value := range v {
+			switch key {
+			case "traceId", "spanId", "parentSpanId":
+				id, err := hex.DecodeString(value.(string))
+				if err != nil {
+					t.Fatalf("%s %q is not hex: %v", key, value, err)
+				}
+				v[key] = base64.StdEncoding.EncodeToString(id)
+			default:
+				base64IDs(t, value)
+			}
+		}
+	case []any:
+		for _, value := range v {
+			base64IDs(t, value)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package telemetry
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/sdk/metric"
+)
+
+// PrometheusHandler returns the handler serving metrics in the Prometheus
+// exposition format, or nil when the Prometheus exporter is not enabled
+// through WithPrometheus or OTEL_METRICS_EXPORTER.
+func PrometheusHandler() http.Handler {
+	return loadState().prometheus
+}
+
+// prometheusEnabled reports whether OTEL_METRICS_EXPORTER lists "prometheus".
+func prometheusEnabled() bool {
+	return slices.Contains(exporterNamesFromEnv(envMetricsExporter), exporterPrometheus)
+}
+
+// newPrometheusReader returns a pull based metric reader together with the
+// handler exposing it. Each reader has its own registry so the Go client's
+// default collectors are not mixed in. Exemplars are only part of the
+// OpenMetrics format, which the handler serves to scrapers asking for it.
+func newPrometheusReader() (metric.Reader, http.Handler, error) {
+	registry := prometheus.NewRegistry()
+	exporter, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return exporter, promhttp.HandlerFor(registry, promhttp.HandlerOpts{EnableOpenMetrics: true}), nil
+}
@@ -0,0 +1,50 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+func TestPrometheusHandler(t *testing.T) {
+	setupRuntime(t)
+	if handler := PrometheusHandler(); handler != nil {
+		t.Fatalf("PrometheusHandler() = %v without WithPrometheus, want nil", handler)
+	}
+
+	setupRuntime(t, WithPrometheus())
+	counter, err := otel.Meter("prometheus-test").Int64Counter("notification.sent")
+	if err != nil {
+		t.Fatalf("counter: %v", err)
+	}
+	counter.Add(context.Background(), 3, metric.WithAttributes(attribute.String("channel", "email")))
+
+	handler := PrometheusHandler()
+	if handler == nil {
+		t.Fatal("PrometheusHandler() = nil with WithPrometheus")
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`notification_sent_total{channel="email",otel_scope_name="prometheus-test"`,
+		`target_info{`,
+		`service_name="runtime-test"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("scrape does not contain %s:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "go_goroutines") {
+		t.Errorf("scrape contains the default Go collectors")
+	}
+}
@@ -0,0 +1,40 @@
+package telemetry
+
+import (
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/contrib/propagators/autoprop"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const envPropagators = "OTEL_PROPAGATORS"
+
+// newPropagator composes the propagators configured in code or, failing
+// that, the ones named in OTEL_PROPAGATORS (tracecontext, baggage, b3,
+// b3multi, jaeger, xray, ottrace or none) in the given order. W3C
+// TraceContext and Baggage are used when neither is set.
+func newPropagator(cfg *config) (propagation.TextMapPropagator, error) {
+	if len(cfg.propagators) > 0 {
+		return propagation.NewCompositeTextMapPropagator(cfg.propagators...), nil
+	}
+
+	names := cfg.propagatorNames
+	if len(names) == 0 {
+		if value := os.Getenv(envPropagators); value != "" {
+			names = strings.Split(value, ",")
+		}
+	}
+	if len(names) > 0 {
+		normalized := make([]string, 0, len(names))
+		for _, name := range names {
+			normalized = append(normalized, strings.ToLower(strings.TrimSpace(name)))
+		}
+		return autoprop.TextMapPropagator(normalized...)
+	}
+
+	return propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	), nil
+}
@@ -0,0 +1,268 @@
+package telemetry
+
+import (
+	"context"
+	"path"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const redactedValue = "[REDACTED]"
+
+// RedactionRule redacts span attributes, span event attributes and zap
+// fields whose key matches Pattern.
+type RedactionRule struct {
+	// Pattern is a path.Match pattern, e.g. "*email*" or "*.token".
+	Pattern string
+	// Redact replaces the value. Non string values are formatted first. A
+	// nil Redact keeps the value, exempting the key from the rules after it.
+	Redact RedactFunc
+}
+
+// DefaultRedactionRules masks e-mail addresses and hashes user and device
+// identifiers, including promoted baggage members.
+var DefaultRedactionRules = []RedactionRule{
+	{Pattern: "*email*", Redact: MaskEmail},
+	{Pattern: "*user_id*", Redact: HashValue},
+	{Pattern: "*user.id*", Redact: HashValue},
+	{Pattern: "*device_id*", Redact: HashValue},
+	{Pattern: "*device.id*", Redact: HashValue},
+}
+
+// MaskEmail keeps the first character of the local part and the domain,
+// e.g. "j***@example.com".
+func MaskEmail(value string) string {
+	local, domain, ok := strings.Cut(value, "@")
+	if !ok || local == "" {
+		return MaskValue(value)
+	}
+	return local[:1] + "***@" + domain
+}
+
+// RedactAll replaces any value with a fixed placeholder.
+func RedactAll(string) string {
+	return redactedValue
+}
+
+// defaultRedactor applies DefaultRedactionRules to promoted baggage members.
+var defaultRedactor = newRedactor(DefaultRedactionRules)
+
+type redactor struct {
+	rules []RedactionRule
+}
+
+func newRedactor(rules []RedactionRule) *redactor {
+	return &redactor{rules: rules}
+}
+
+// lookup returns the redaction of the first rule matching key.
+func (r *redactor) lookup(key string) RedactFunc {
+	for _, rule := range r.rules {
+		if ok, _ := path.Match(rule.Pattern, key); ok {
+			return rule.Redact
+		}
+	}
+	return nil
+}
+
+// attributes returns attrs with matching values redacted and whether any
+// was. The slice is only copied when something changes.
+func (r *redactor) attributes(attrs []attribute.KeyValue) ([]attribute.KeyValue, bool) {
+	var out []attribute.KeyValue
+	for i, attr := range attrs {
+		redact := r.lookup(string(attr.Key))
+		if redact == nil {
+			continue
+		}
+		if out == nil {
+			out = append([]attribute.KeyValue(nil), attrs...)
+		}
+		out[i] = attr.Key.String(redact(attr.Value.Emit()))
+	}
+	if out == nil {
+		return attrs, false
+	}
+	return out, true
+}
+
+// fields returns fields with matching values redacted.
+func (r *redactor) fields(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, field := range fields {
+		redact := r.lookup(field.Key)
+		if redact == nil || field.Type == zapcore.SkipType {
+			continue
+		}
+		if out == nil {
+			out = append([]zapcore.Field(nil), fields...)
+		}
+		out[i] = zap.String(field.Key, redact(fieldString(field)))
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+func fieldString(field zapcore.Field) string {
+	switch field.Type {
+	case zapcore.StringType:
+		return field.String
+	case zapcore.ByteStringType:
+		return string(field.Interface.([]byte))
+	case zapcore.Int64Type, zapcore.Int32Type, zapcore.Int16Type, zapcore.Int8Type:
+		return strconv.FormatInt(field.Integer, 10)
+	case zapcore.Uint64Type, zapcore.Uint32Type, zapcore.Uint16Type, zapcore.Uint8Type:
+		return strconv.FormatUint(uint64(field.Integer), 10)
+	default:
+		// Structured values cannot be redacted member by member.
+		return redactedValue
+	}
+}
+
+// NewRedactingSpanProcessor redacts the attributes and event attributes of
+// ended spans matching rules before passing them to next, typically a batch
+// span processor.
+func NewRedactingSpanProcessor(next trace.SpanProcessor, rules ...RedactionRule) trace.SpanProcessor {
+	return &redactingSpanProcessor{
+		next:     next,
+		redactor: newRedactor(rules),
+	}
+}
+
+type redactingSpanProcessor struct {
+	next     trace.SpanProcessor
+	redactor *redactor
+}
+
+func (p *redactingSpanProcessor) OnStart(parent context.Context, s trace.ReadWriteSpan) {
+	p.next.OnStart(parent, s)
+}
+
+func (p *redactingSpanProcessor) OnEnd(s trace.ReadOnlySpan) {
+	attrs, redacted := p.redactor.attributes(s.Attributes())
+
+	events := s.Events()
+	var redactedEvents []trace.Event
+	for i, event := range events {
+		eventAttrs, ok := p.redactor.attributes(event.Attributes)
+		if !ok {
+			continue
+		}
+		if redactedEvents == nil {
+			redactedEvents = append([]trace.Event(nil), events...)
+		}
+		redactedEvents[i].Attributes = eventAttrs
+	}
+
+	if !redacted && redactedEvents == nil {
+		p.next.OnEnd(s)
+		return
+	}
+	if redactedEvents == nil {
+		redactedEvents = events
+	}
+	p.next.OnEnd(redactedSpan{ReadOnlySpan: s, attributes: attrs, events: redactedEvents})
+}
+
+func (p *redactingSpanProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+func (p *redactingSpanProcessor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+// redactedSpan is an ended span with redacted attributes and events.
+type redactedSpan struct {
+	trace.ReadOnlySpan
+	attributes []attribute.KeyValue
+	events     []trace.Event
+}
+
+func (s redactedSpan) Attributes() []attribute.KeyValue {
+	return s.attributes
+}
+
+func (s redactedSpan) Events() []trace.Event {
+	return s.events
+}
+
+// NewRedactingLogProcessor redacts the attributes of records matching rules.
+// It must be registered before the processors exporting or recording them.
+func NewRedactingLogProcessor(rules ...RedactionRule) log.Processor {
+	return &redactingLogProcessor{redactor: newRedactor(rules)}
+}
+
+type redactingLogProcessor struct {
+	redactor *redactor
+}
+
+func (p *redactingLogProcessor) OnEmit(_ context.Context, record *log.Record) error {
+	attrs := make([]otellog.KeyValue, 0, record.AttributesLen())
+	redacted := false
+	record.WalkAttributes(func(attr otellog.KeyValue) bool {
+		if redact := p.redactor.lookup(attr.Key); redact != nil {
+			attr.Value = otellog.StringValue(redact(logValueString(attr.Value)))
+			redacted = true
+		}
+		attrs = append(attrs, attr)
+		return true
+	})
+	if redacted {
+		record.SetAttributes(attrs...)
+	}
+	return nil
+}
+
+func (p *redactingLogProcessor) ForceFlush(context.Context) error { return nil }
+
+func (p *redactingLogProcessor) Shutdown(context.Context) error { return nil }
+
+func logValueString(value otellog.Value) string {
+	switch value.Kind() {
+	case otellog.KindMap, otellog.KindSlice, otellog.KindBytes:
+		// Structured values cannot be redacted member by member.
+		return redactedValue
+	default:
+		return value.String()
+	}
+}
+
+// redactingCore redacts fields before they reach the wrapped core.
+type redactingCore struct {
+	zapcore.Core
+	redactor *redactor
+}
+
+func newRedactingCore(core zapcore.Core, r *redactor) zapcore.Core {
+	if r == nil {
+		return core
+	}
+	return &redactingCore{Core: core, redactor: r}
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{
+		Core:     c.Core.With(c.redactor.fields(fields)),
+		redactor: c.redactor,
+	}
+}
+
+func (c *redactingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, c.redactor.fields(fields))
+}
@@ -0,0 +1,212 @@
+package telemetry
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+func TestRedactingSpanProcessor(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(
+		NewRedactingSpanProcessor(recorder, DefaultRedactionRules...),
+	))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	tracer := provider.Tracer("redact-test")
+
+	_, span := tracer.Start(context.Background(), "redacted")
+	span.SetAttributes(attribute.String("email.to", "jane@example.com"), attribute.String("channel", "email"))
+	span.SetAttributes(attribute.String("baggage.user_id", "42"))
+	span.AddEvent("sent", oteltrace.WithAttributes(attribute.Int64("user_id", 42)))
+	span.End()
+
+	_, span = tracer.Start(context.Background(), "clean")
+	span.SetAttributes(attribute.String("channel", "push"))
+	span.End()
+
+	ended := recorder.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(ended))
+	}
+
+	redacted := ended[0]
+	got, want := attribute.NewSet(redacted.Attributes()...), attribute.NewSet(
+		attribute.String("email.to", "j***@example.com"),
+		attribute.String("channel", "email"),
+		attribute.String("baggage.user_id", HashValue("42")),
+	)
+	if !got.Equals(&want) {
+		t.Errorf("attributes = %v, want %v", got.ToSlice(), want.ToSlice())
+	}
+	wantEvent := attribute.String("user_id", HashValue("42"))
+	if got := redacted.Events()[0].Attributes; len(got) != 1 || got[0] != wantEvent {
+		t.Errorf("event attributes = %v, want %v", got, wantEvent)
+	}
+
+	if _, ok := ended[1].(redactedSpan); ok {
+		t.Errorf("span without matching attributes was copied")
+	}
+}
+
+func TestHashValue(t *testing.T) {
+	hashed := HashValue("42")
+	if hashed == "42" || !strings.HasPrefix(hashed, hashPrefix) {
+		t.Errorf("HashValue(42) = %q, want an HMAC", hashed)
+	}
+	if again := HashValue(hashed); again != hashed {
+		t.Errorf("HashValue(%q) = %q, want it unchanged", hashed, again)
+	}
+
+	hashKeyed := func(key string) string {
+		t.Helper()
+		shutdown, err := SetupOTelSDK(context.Background(), "hash-test",
+			WithTraceExporter(ExporterNone),
+			WithMetricExporter(ExporterNone),
+			WithLogExporter(ExporterNone),
+			WithHashKey([]byte(key)),
+		)
+		if err != nil {
+			t.Fatalf("SetupOTelSDK: %v", err)
+		}
+		defer func() { _ = shutdown(context.Background()) }()
+		return HashValue("42")
+	}
+	first, again, other := hashKeyed("first"), hashKeyed("first"), hashKeyed("other")
+	if first != again || first == other || first == hashed {
+		t.Errorf("hashes = %q, %q with the same key and %q, %q with others, want them equal only for the same key", first, again, other, hashed)
+	}
+}
+
+func TestBaggageRedaction(t *testing.T) {
+	ctx, err := ContextWithBaggage(context.Background(), "user_id", "42", "user_email", "jane@example.com", "tenant_id", "acme")
+	if err != nil {
+		t.Fatalf("baggage: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		cfg  BaggageConfig
+		want map[string]string
+	}{
+		{
+			name: "default rules",
+			cfg:  BaggageConfig{AllowedKeys: []string{"user_id", "user_email", "tenant_id"}},
+			want: map[string]string{"user_id": HashValue("42"), "user_email": "j***@example.com", "tenant_id": "acme"},
+		},
+		{
+			name: "configured",
+			cfg: BaggageConfig{
+				AllowedKeys: []string{"user_id", "tenant_id"},
+				Redact:      map[string]RedactFunc{"user_id": nil, "tenant_id": RedactAll},
+			},
+			want: map[string]string{"user_id": "42", "tenant_id": redactedValue},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string]string{}
+			newBaggagePromoter(tt.cfg).members(ctx, func(key, value string) {
+				got[key] = value
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("members = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("logger", func(t *testing.T) {
+		setupRuntime(t, WithLoggerBaggageKeys("user_id"))
+
+		want := zap.String("baggage.user_id", HashValue("42"))
+		if fields := LogFields(ctx); len(fields) != 2 || !fields[1].Equals(want) {
+			t.Errorf("fields = %v, want the context and %v", fields, want)
+		}
+	})
+}
+
+func TestSetupOTelSDKRedactsRegisteredProcessors(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	logs := &logRecorder{}
+	setupRuntime(t,
+		WithSampler(sdktrace.AlwaysSample()),
+		WithRedactionRules(DefaultRedactionRules...),
+		WithSpanProcessor(spans),
+		WithLogProcessor(logs),
+	)
+
+	_, span := otel.Tracer("redact-test").Start(context.Background(), "redacted")
+	span.SetAttributes(attribute.String("email.to", "jane@example.com"))
+	span.End()
+
+	var record otellog.Record
+	record.SetBody(otellog.StringValue("otel"))
+	record.AddAttributes(otellog.String("email.to", "jane@example.com"))
+	global.GetLoggerProvider().Logger("redact-test").Emit(context.Background(), record)
+
+	logger := zap.New(zap.NewNop().Core(), ZapOption("redact-test"))
+	logger.Info("zap", zap.Int64("user_id", 42))
+
+	ended := spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(ended))
+	}
+	wantSpan := attribute.String("email.to", "j***@example.com")
+	if got := ended[0].Attributes(); len(got) != 1 || got[0] != wantSpan {
+		t.Errorf("span attributes = %v, want %v", got, wantSpan)
+	}
+
+	want := map[string]otellog.KeyValue{
+		"otel": otellog.String("email.to", "j***@example.com"),
+		// Redacted once, although the zap entry passes a redacting core.
+		"zap": otellog.String("user_id", HashValue("42")),
+	}
+	records := logs.get()
+	if len(records) != len(want) {
+		t.Fatalf("recorded %d log records, want %d", len(records), len(want))
+	}
+	for _, record := range records {
+		body := record.Body().AsString()
+		var attrs []otellog.KeyValue
+		record.WalkAttributes(func(attr otellog.KeyValue) bool {
+			attrs = append(attrs, attr)
+			return true
+		})
+		if len(attrs) != 1 || !attrs[0].Equal(want[body]) {
+			t.Errorf("log %q attributes = %v, want %v", body, attrs, want[body])
+		}
+	}
+}
+
+// logRecorder records the emitted log records.
+type logRecorder struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+func (p *logRecorder) OnEmit(_ context.Context, record *sdklog.Record) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.records = append(p.records, record.Clone())
+	return nil
+}
+
+func (p *logRecorder) Shutdown(context.Context) error   { return nil }
+func (p *logRecorder) ForceFlush(context.Context) error { return nil }
+
+func (p *logRecorder) get() []sdklog.Record {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]sdklog.Record(nil), p.records...)
+}
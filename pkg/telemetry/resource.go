@@ -0,0 +1,98 @@
+package telemetry
+
+import (
+	"context"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const k8sNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// newResource describes the running service. Attributes are applied from the
+// least to the most specific source, so OTEL_RESOURCE_ATTRIBUTES and
+// OTEL_SERVICE_NAME always win over what is detected or configured in code.
+func newResource(ctx context.Context, serviceName string, cfg *config) (*resource.Resource, error) {
+	serviceVersion := cfg.serviceVersion
+	if serviceVersion == "" {
+		serviceVersion = buildVersion()
+	}
+
+	serviceInstanceID := cfg.serviceInstanceID
+	if serviceInstanceID == "" {
+		serviceInstanceID = uuid.NewString()
+	}
+
+	attrs := []attribute.KeyValue{
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(serviceVersion),
+		semconv.ServiceInstanceID(serviceInstanceID),
+	}
+	attrs = append(attrs, cfg.resourceAttributes...)
+
+	return resource.New(ctx,
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithOS(),
+		resource.WithProcess(),
+		resource.WithContainer(),
+		resource.WithDetectors(k8sDetector{}),
+		resource.WithAttributes(attrs...),
+		resource.WithFromEnv(),
+	)
+}
+
+// buildVersion returns the module version embedded by the Go toolchain.
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+// k8sDetector detects Kubernetes attributes from the environment exposed to
+// every pod and from the downward API variables POD_NAME, POD_NAMESPACE and
+// NODE_NAME when they are set.
+type k8sDetector struct{}
+
+func (k8sDetector) Detect(context.Context) (*resource.Resource, error) {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return resource.Empty(), nil
+	}
+
+	var attrs []attribute.KeyValue
+	if pod := firstNonEmpty(os.Getenv("POD_NAME"), os.Getenv("HOSTNAME")); pod != "" {
+		attrs = append(attrs, semconv.K8SPodName(pod))
+	}
+	if namespace := firstNonEmpty(os.Getenv("POD_NAMESPACE"), readK8sNamespace()); namespace != "" {
+		attrs = append(attrs, semconv.K8SNamespaceName(namespace))
+	}
+	if node := os.Getenv("NODE_NAME"); node != "" {
+		attrs = append(attrs, semconv.K8SNodeName(node))
+	}
+
+	return resource.NewWithAttributes(semconv.SchemaURL, attrs...), nil
+}
+
+func readK8sNamespace() string {
+	b, err := os.ReadFile(k8sNamespaceFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestK8sDetector(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []attribute.KeyValue
+	}{
+		{
+			name: "outside kubernetes",
+			env:  map[string]string{"POD_NAME": "notification-7d9f", "NODE_NAME": "node-1"},
+		},
+		{
+			name: "downward API",
+			env: map[string]string{
+				"KUBERNETES_SERVICE_HOST": "10.0.0.1",
+				"HOSTNAME":                "ignored",
+				"POD_NAME":                "notification-7d9f",
+				"POD_NAMESPACE":           "messaging",
+				"NODE_NAME":               "node-1",
+			},
+			want: []attribute.KeyValue{
+				semconv.K8SPodName("notification-7d9f"),
+				semconv.K8SNamespaceName("messaging"),
+				semconv.K8SNodeName("node-1"),
+			},
+		},
+		{
+			name: "pod name from hostname",
+			env: map[string]string{
+				"KUBERNETES_SERVICE_HOST": "10.0.0.1",
+				"HOSTNAME":                "notification-7d9f",
+				"POD_NAMESPACE":           "messaging",
+			},
+			want: []attribute.KeyValue{
+				semconv.K8SPodName("notification-7d9f"),
+				semconv.K8SNamespaceName("messaging"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"KUBERNETES_SERVICE_HOST", "HOSTNAME", "POD_NAME", "POD_NAMESPACE", "NODE_NAME"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			res, err := k8sDetector{}.Detect(context.Background())
+			if err != nil {
+				t.Fatalf("Detect: %v", err)
+			}
+			got, want := res.Set(), attribute.NewSet(tt.want...)
+			if !got.Equals(&want) {
+				t.Errorf("attributes = %v, want %v", got.ToSlice(), want.ToSlice())
+			}
+		})
+	}
+}
+
+func TestNewResourcePrecedence(t *testing.T) {
+	t.Setenv("OTEL_SERVICE_NAME", "")
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "deployment.environment=staging")
+	cfg := newConfig(
+		WithServiceVersion("v1.2.3"),
+		WithResourceAttributes(
+			attribute.String("deployment.environment", "production"),
+			attribute.String("team", "notification"),
+		),
+	)
+
+	res, err := newResource(context.Background(), "notification-server", cfg)
+	if err != nil {
+		t.Fatalf("newResource: %v", err)
+	}
+	for key, want := range map[attribute.Key]string{
+		semconv.ServiceNameKey:    "notification-server",
+		semconv.ServiceVersionKey: "v1.2.3",
+		"deployment.environment":  "staging",
+		"team":                    "notification",
+	} {
+		if got, _ := res.Set().Value(key); got.AsString() != want {
+			t.Errorf("%s = %q, want %q", key, got.AsString(), want)
+		}
+	}
+
+	t.Setenv("OTEL_SERVICE_NAME", "renamed")
+	if res, err = newResource(context.Background(), "notification-server", cfg); err != nil {
+		t.Fatalf("newResource: %v", err)
+	}
+	if got, _ := res.Set().Value(semconv.ServiceNameKey); got.AsString() != "renamed" {
+		t.Errorf("service.name = %q, want the OTEL_SERVICE_NAME", got.AsString())
+	}
+}
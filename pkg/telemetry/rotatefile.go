@@ -0,0 +1,230 @@
+package telemetry
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+)
+
+const rotatedTimeFormat = "20060102T150405.000000000"
+
+// rotatingFile is an append only file that is rotated once it grows beyond
+// maxSize bytes or gets older than maxAge. Rotated segments are renamed with
+// a timestamp suffix, optionally gzip compressed, and only the newest
+// maxBackups segments are kept.
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	compress   bool
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	// maintenance serialises compression and cleanup of rotated segments,
+	// wg lets Close wait for them.
+	maintenance sync.Mutex
+	wg          sync.WaitGroup
+}
+
+func newRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int, compress bool) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	f := &rotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+		compress:   compress,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return errors.Join(err, file.Close())
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = time.Now()
+	return nil
+}
+
+// Write appends p, rotating first when p would not fit in the current
+// segment. Each call is written as a whole to a single segment.
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	expired := f.maxAge > 0 && time.Since(f.openedAt) >= f.maxAge
+	full := f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize
+	if expired || full {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate moves the current segment aside and opens a new one. The caller
+// holds f.mu.
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+
+	ext := filepath.Ext(f.path)
+	rotated := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(f.path, ext), time.Now().UTC().Format(rotatedTimeFormat), ext)
+	if err := os.Rename(f.path, rotated); err != nil {
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		f.maintenance.Lock()
+		defer f.maintenance.Unlock()
+
+		if f.compress {
+			if err := f.compressRotated(); err != nil {
+				otel.Handle(err)
+			}
+		}
+		if err := f.removeExpired(); err != nil {
+			otel.Handle(err)
+		}
+	}()
+	return nil
+}
+
+// segments returns the rotated segments matching suffix, oldest first. The
+// timestamp suffix sorts chronologically.
+func (f *rotatingFile) segments(suffix string) ([]string, error) {
+	ext := filepath.Ext(f.path)
+	segments, err := filepath.Glob(strings.TrimSuffix(f.path, ext) + "-*" + ext + suffix)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(segments)
+	return segments, nil
+}
+
+// compressRotated gzips every rotated segment not compressed yet, so a
+// segment skipped by an earlier failure is picked up again.
+func (f *rotatingFile) compressRotated() error {
+	segments, err := f.segments("")
+	if err != nil {
+		return err
+	}
+	var errs error
+	for _, segment := range segments {
+		errs = errors.Join(errs, gzipFile(segment))
+	}
+	return errs
+}
+
+// removeExpired deletes rotated segments beyond the newest maxBackups.
+func (f *rotatingFile) removeExpired() error {
+	if f.maxBackups <= 0 {
+		return nil
+	}
+
+	segments, err := f.segments("*")
+	if err != nil {
+		return err
+	}
+	if len(segments) <= f.maxBackups {
+		return nil
+	}
+
+	var errs error
+	for _, segment := range segments[:len(segments)-f.maxBackups] {
+		errs = errors.Join(errs, os.Remove(segment))
+	}
+	return errs
+}
+
+func gzipFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(path + ".gz")
+		}
+	}()
+
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err != nil {
+		return errors.Join(err, zw.Close(), dst.Close())
+	}
+	if err = errors.Join(zw.Close(), dst.Close()); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Sync flushes the current segment to disk.
+func (f *rotatingFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+// Close closes the current segment and waits for pending compression.
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+
+	f.wg.Wait()
+	return err
+}
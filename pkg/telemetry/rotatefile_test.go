@@ -0,0 +1,148 @@
+package telemetry
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	f := newTestRotatingFile(t, path, 10, 0, 0, false)
+
+	writeLines(t, f, "12345\n", "67890\n", "a large line\n")
+	if err := f.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	// A line larger than MaxSize still goes to a segment of its own.
+	assertFileContent(t, path, "a large line\n")
+	assertSegments(t, path, "", "12345\n", "67890\n")
+}
+
+func TestRotatingFileAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	f := newTestRotatingFile(t, path, 0, time.Hour, 0, false)
+
+	writeLines(t, f, "first\n", "second\n")
+	f.mu.Lock()
+	f.openedAt = f.openedAt.Add(-time.Hour)
+	f.mu.Unlock()
+	writeLines(t, f, "third\n")
+	if err := f.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	assertFileContent(t, path, "third\n")
+	assertSegments(t, path, "", "first\nsecond\n")
+}
+
+func TestRotatingFileCompression(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	f := newTestRotatingFile(t, path, 6, 0, 0, true)
+
+	writeLines(t, f, "12345\n", "67890\n", "abcde\n")
+	// Close waits for the compression running in the background.
+	if err := f.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	assertFileContent(t, path, "abcde\n")
+	assertSegments(t, path, ".gz", "12345\n", "67890\n")
+	assertSegments(t, path, "")
+}
+
+func TestRotatingFileMaxBackups(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		suffix := ""
+		if compress {
+			suffix = ".gz"
+		}
+		t.Run("compress="+strconv.FormatBool(compress), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "traces.jsonl")
+			f := newTestRotatingFile(t, path, 6, 0, 2, compress)
+
+			writeLines(t, f, "line1\n", "line2\n", "line3\n", "line4\n", "line5\n")
+			if err := f.Close(); err != nil {
+				t.Fatalf("close: %v", err)
+			}
+
+			assertFileContent(t, path, "line5\n")
+			assertSegments(t, path, suffix, "line3\n", "line4\n")
+		})
+	}
+}
+
+func newTestRotatingFile(t *testing.T, path string, maxSize int64, maxAge time.Duration, maxBackups int, compress bool) *rotatingFile {
+	t.Helper()
+
+	f, err := newRotatingFile(path, maxSize, maxAge, maxBackups, compress)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+	return f
+}
+
+func writeLines(t *testing.T, f *rotatingFile, lines ...string) {
+	t.Helper()
+
+	for _, line := range lines {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("write %q: %v", line, err)
+		}
+	}
+}
+
+func assertFileContent(t *testing.T, path, want string) {
+	t.Helper()
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	if string(got) != want {
+		t.Errorf("%s = %q, want %q", filepath.Base(path), got, want)
+	}
+}
+
+// assertSegments checks the content of the rotated segments of path ending
+// in suffix, oldest first, gunzipping them when suffix is ".gz".
+func assertSegments(t *testing.T, path, suffix string, want ...string) {
+	t.Helper()
+
+	ext := filepath.Ext(path)
+	segments, err := filepath.Glob(strings.TrimSuffix(path, ext) + "-*" + ext + suffix)
+	if err != nil {
+		t.Fatalf("glob: %v", err)
+	}
+	if len(segments) != len(want) {
+		t.Fatalf("%d segments ending in %q, want %d: %v", len(segments), ext+suffix, len(want), segments)
+	}
+
+	for i, segment := range segments {
+		file, err := os.Open(segment)
+		if err != nil {
+			t.Fatalf("open %s: %v", segment, err)
+		}
+		var r io.Reader = file
+		if suffix == ".gz" {
+			if r, err = gzip.NewReader(file); err != nil {
+				t.Fatalf("gunzip %s: %v", segment, err)
+			}
+		}
+		got, err := io.ReadAll(r)
+		_ = file.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", segment, err)
+		}
+		if string(got) != want[i] {
+			t.Errorf("segment %d = %q, want %q", i, got, want[i])
+		}
+	}
+}
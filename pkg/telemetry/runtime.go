@@ -0,0 +1,182 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	goruntime "runtime"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/process"
+	"go.opentelemetry.io/contrib/instrumentation/host"
+	"go.opentelemetry.io/contrib/instrumentation/runtime"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric"
+)
+
+// MetricGroup selects a set of process level metrics collected alongside
+// the application metrics.
+type MetricGroup string
+
+const (
+	// MetricGroupRuntime reports Go memory and goroutine metrics of the
+	// contrib runtime instrumentation: process.runtime.go.* or, with
+	// OTEL_GO_X_DEPRECATED_RUNTIME_METRICS=false, go.memory.*,
+	// go.goroutine.count, go.processor.limit and go.config.gogc.
+	MetricGroupRuntime MetricGroup = "runtime"
+	// MetricGroupGC reports garbage collections and the time the world was
+	// stopped for them (go.gc.count, go.gc.pause.time), which the go.*
+	// runtime metrics leave out.
+	MetricGroupGC MetricGroup = "gc"
+	// MetricGroupProcess reports the CPU time and open file descriptors of
+	// the process (process.cpu.time, process.open_file_descriptor.count).
+	MetricGroupProcess MetricGroup = "process"
+	// MetricGroupHost reports host CPU time, memory and network I/O
+	// (system.cpu.time, system.memory.*, system.network.io).
+	MetricGroupHost MetricGroup = "host"
+)
+
+// envMetricGroups lists the groups enabled when WithMetricGroups is not
+// used, e.g. "runtime,gc,process,host".
+const envMetricGroups = "OTEL_GO_METRIC_GROUPS"
+
+// metricGroupsFromEnv parses OTEL_GO_METRIC_GROUPS.
+func metricGroupsFromEnv() ([]MetricGroup, error) {
+	var groups []MetricGroup
+	for _, name := range strings.Split(os.Getenv(envMetricGroups), ",") {
+		switch group := MetricGroup(strings.ToLower(strings.TrimSpace(name))); group {
+		case "":
+		case MetricGroupRuntime, MetricGroupGC, MetricGroupProcess, MetricGroupHost:
+			groups = append(groups, group)
+		default:
+			return nil, fmt.Errorf("telemetry: unsupported %s value %q", envMetricGroups, name)
+		}
+	}
+	return groups, nil
+}
+
+// hostProcessCPUTimeView drops the process.cpu.time reported by the host
+// instrumentation, reported by MetricGroupProcess instead.
+var hostProcessCPUTimeView = metric.NewView(
+	metric.Instrument{Name: "process.cpu.time", Scope: instrumentation.Scope{Name: host.ScopeName}},
+	metric.Stream{Aggregation: metric.AggregationDrop{}},
+)
+
+// startMetricGroups starts collecting the enabled groups from provider and
+// returns the function stopping the callbacks registered here. The runtime
+// and host instrumentation cannot be stopped on their own; they stop with
+// provider.
+func startMetricGroups(provider otelmetric.MeterProvider, cfg *config) (stop func(context.Context) error, err error) {
+	groups := cfg.metricGroups
+	if groups == nil {
+		if groups, err = metricGroupsFromEnv(); err != nil {
+			return nil, err
+		}
+	}
+
+	var registrations []otelmetric.Registration
+	stop = func(context.Context) error {
+		var err error
+		for _, registration := range registrations {
+			err = errors.Join(err, registration.Unregister())
+		}
+		registrations = nil
+		return err
+	}
+
+	meter := provider.Meter(instrumentationName)
+	for _, group := range groups {
+		var registration otelmetric.Registration
+		switch group {
+		case MetricGroupRuntime:
+			err = runtime.Start(runtime.WithMeterProvider(provider))
+		case MetricGroupGC:
+			registration, err = registerGCMetrics(meter)
+		case MetricGroupProcess:
+			registration, err = registerProcessMetrics(meter)
+		case MetricGroupHost:
+			err = host.Start(host.WithMeterProvider(provider))
+		default:
+			err = fmt.Errorf("telemetry: unknown metric group %q", group)
+		}
+		if err != nil {
+			return nil, errors.Join(err, stop(context.Background()))
+		}
+		if registration != nil {
+			registrations = append(registrations, registration)
+		}
+	}
+	return stop, nil
+}
+
+func registerGCMetrics(meter otelmetric.Meter) (otelmetric.Registration, error) {
+	count, err := meter.Int64ObservableCounter("go.gc.count",
+		otelmetric.WithDescription("Completed garbage collection cycles."),
+		otelmetric.WithUnit("{gc_cycle}"))
+	if err != nil {
+		return nil, err
+	}
+
+	pauseTime, err := meter.Float64ObservableCounter("go.gc.pause.time",
+		otelmetric.WithDescription("Time the world was stopped for garbage collection."),
+		otelmetric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	return meter.RegisterCallback(func(_ context.Context, o otelmetric.Observer) error {
+		var stats goruntime.MemStats
+		goruntime.ReadMemStats(&stats)
+
+		o.ObserveInt64(count, int64(stats.NumGC))
+		o.ObserveFloat64(pauseTime, time.Duration(stats.PauseTotalNs).Seconds())
+		return nil
+	}, count, pauseTime)
+}
+
+func registerProcessMetrics(meter otelmetric.Meter) (otelmetric.Registration, error) {
+	pid := os.Getpid()
+	if pid > math.MaxInt32 {
+		return nil, fmt.Errorf("telemetry: invalid process ID %d", pid)
+	}
+	proc, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return nil, err
+	}
+
+	cpuTime, err := meter.Float64ObservableCounter("process.cpu.time",
+		otelmetric.WithDescription("CPU time spent by the process, by state (user, system)."),
+		otelmetric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	fds, err := meter.Int64ObservableUpDownCounter("process.open_file_descriptor.count",
+		otelmetric.WithDescription("Number of file descriptors in use by the process."),
+		otelmetric.WithUnit("{file_descriptor}"))
+	if err != nil {
+		return nil, err
+	}
+
+	return meter.RegisterCallback(func(ctx context.Context, o otelmetric.Observer) error {
+		times, err := proc.TimesWithContext(ctx)
+		if err != nil {
+			return err
+		}
+		// The attributes of the host instrumentation, which reported the
+		// metric before.
+		o.ObserveFloat64(cpuTime, times.User, otelmetric.WithAttributeSet(host.AttributeCPUTimeUser))
+		o.ObserveFloat64(cpuTime, times.System, otelmetric.WithAttributeSet(host.AttributeCPUTimeSystem))
+
+		n, err := proc.NumFDsWithContext(ctx)
+		if err != nil {
+			return err
+		}
+		o.ObserveInt64(fds, int64(n))
+		return nil
+	}, cpuTime, fds)
+}
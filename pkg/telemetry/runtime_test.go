@@ -0,0 +1,62 @@
+package telemetry
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestMetricGroups(t *testing.T) {
+	tests := []struct {
+		group MetricGroup
+		// want lists the metrics of the group, or their prefix when
+		// ending with a dot.
+		want []string
+	}{
+		{group: MetricGroupRuntime, want: []string{"process.runtime.go.mem.", "process.runtime.go.goroutines"}},
+		{group: MetricGroupGC, want: []string{"go.gc.count", "go.gc.pause.time"}},
+		{group: MetricGroupProcess, want: []string{"process.cpu.time", "process.open_file_descriptor.count"}},
+		{group: MetricGroupHost, want: []string{"system.cpu.time", "system.memory.usage", "system.memory.utilization", "system.network.io"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.group), func(t *testing.T) {
+			reader := sdkmetric.NewManualReader()
+			setupRuntime(t, WithMetricGroups(tt.group), WithMetricReader(reader))
+
+			var rm metricdata.ResourceMetrics
+			if err := reader.Collect(context.Background(), &rm); err != nil {
+				t.Fatalf("collect: %v", err)
+			}
+			var names []string
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					names = append(names, m.Name)
+				}
+			}
+
+			for _, want := range tt.want {
+				if !slices.ContainsFunc(names, func(name string) bool {
+					return name == want || strings.HasSuffix(want, ".") && strings.HasPrefix(name, want)
+				}) {
+					t.Errorf("metrics = %v, want %s", names, want)
+				}
+			}
+			// The metrics of the other groups are left out.
+			for _, other := range tests {
+				if other.group == tt.group {
+					continue
+				}
+				for _, name := range other.want {
+					if slices.Contains(names, name) {
+						t.Errorf("metrics = %v, want no %s of group %s", names, name, other.group)
+					}
+				}
+			}
+		})
+	}
+}
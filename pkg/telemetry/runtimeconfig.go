@@ -0,0 +1,303 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// errNotSetUp is returned when the runtime settings are changed before
+// SetupOTelSDK.
+var errNotSetUp = errors.New("telemetry: SDK is not set up")
+
+// RuntimeConfig changes the sampling and the log level of a running
+// process. Unset fields are left as they are.
+type RuntimeConfig struct {
+	Sampling *SamplingConfig `json:"sampling,omitempty"`
+	// LogLevel requires the level given to WithLogLevel.
+	LogLevel *zapcore.Level `json:"log_level,omitempty"`
+}
+
+// SamplingConfig replaces the sampler configured at setup. The rules given
+// with WithSamplingRules still decide first; the other root spans are
+// sampled by the ratio of the first rule matching their name, or by Ratio,
+// and other spans follow their parent.
+type SamplingConfig struct {
+	Ratio float64             `json:"ratio"`
+	Rules []RouteSamplingRule `json:"rules,omitempty"`
+}
+
+// RouteSamplingRule samples root spans matching Route by Ratio.
+type RouteSamplingRule struct {
+	// Route is a path.Match pattern on the span name, which is the request
+	// path for HTTP server spans, e.g. "/client/notifications/*".
+	Route string  `json:"route"`
+	Ratio float64 `json:"ratio"`
+}
+
+func (c SamplingConfig) validate() error {
+	if c.Ratio < 0 || c.Ratio > 1 {
+		return fmt.Errorf("telemetry: sampling ratio %g is not between 0 and 1", c.Ratio)
+	}
+	for _, rule := range c.Rules {
+		if rule.Ratio < 0 || rule.Ratio > 1 {
+			return fmt.Errorf("telemetry: sampling ratio %g of route %q is not between 0 and 1", rule.Ratio, rule.Route)
+		}
+		if _, err := path.Match(rule.Route, ""); err != nil {
+			return fmt.Errorf("telemetry: invalid route pattern %q: %w", rule.Route, err)
+		}
+	}
+	return nil
+}
+
+func (c SamplingConfig) sampler() trace.Sampler {
+	rules := make([]SamplingRule, 0, len(c.Rules))
+	for _, rule := range c.Rules {
+		rules = append(rules, SamplingRule{SpanName: rule.Route, Sampler: trace.TraceIDRatioBased(rule.Ratio)})
+	}
+	return trace.ParentBased(RuleBasedSampler(trace.TraceIDRatioBased(c.Ratio), rules...))
+}
+
+// RuntimeStatus reports the settings that can be changed at runtime.
+type RuntimeStatus struct {
+	// Sampler describes the sampler in use.
+	Sampler string `json:"sampler"`
+	// Sampling is nil until the sampling is changed at runtime.
+	Sampling  *SamplingConfig `json:"sampling,omitempty"`
+	LogLevel  string          `json:"log_level,omitempty"`
+	UpdatedAt *time.Time      `json:"updated_at,omitempty"`
+	UpdatedBy string          `json:"updated_by,omitempty"`
+}
+
+// switchableSampler delegates to a sampler that can be replaced while the
+// tracer provider is in use.
+type switchableSampler struct {
+	current atomic.Pointer[trace.Sampler]
+}
+
+func newSwitchableSampler(sampler trace.Sampler) *switchableSampler {
+	s := &switchableSampler{}
+	s.set(sampler)
+	return s
+}
+
+func (s *switchableSampler) set(sampler trace.Sampler) {
+	s.current.Store(&sampler)
+}
+
+func (s *switchableSampler) ShouldSample(p trace.SamplingParameters) trace.SamplingResult {
+	return (*s.current.Load()).ShouldSample(p)
+}
+
+func (s *switchableSampler) Description() string {
+	return (*s.current.Load()).Description()
+}
+
+type runtimeControl struct {
+	sampler *switchableSampler
+	// rules are the sampling rules given at setup, kept in front of every
+	// sampler set at runtime.
+	rules []SamplingRule
+	level *zap.AtomicLevel
+
+	mu        sync.Mutex
+	sampling  *SamplingConfig
+	updatedAt time.Time
+	updatedBy string
+}
+
+func newRuntimeControl(fallback trace.Sampler, rules []SamplingRule, level *zap.AtomicLevel) *runtimeControl {
+	c := &runtimeControl{rules: rules, level: level}
+	c.sampler = newSwitchableSampler(c.withRules(fallback))
+	return c
+}
+
+// withRules applies the sampling rules given at setup before fallback.
+func (c *runtimeControl) withRules(fallback trace.Sampler) trace.Sampler {
+	if len(c.rules) == 0 {
+		return fallback
+	}
+	return RuleBasedSampler(fallback, c.rules...)
+}
+
+func (c *runtimeControl) update(source string, update RuntimeConfig) error {
+	if update.Sampling == nil && update.LogLevel == nil {
+		return nil
+	}
+	if update.Sampling != nil {
+		if err := update.Sampling.validate(); err != nil {
+			return err
+		}
+	}
+	if update.LogLevel != nil && c.level == nil {
+		return errors.New("telemetry: log level is not adjustable, see WithLogLevel")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if update.Sampling != nil {
+		sampling := *update.Sampling
+		sampling.Rules = append([]RouteSamplingRule(nil), sampling.Rules...)
+
+		previous := c.sampler.Description()
+		c.sampler.set(c.withRules(sampling.sampler()))
+		c.sampling = &sampling
+		audit("Telemetry sampling changed",
+			zap.String("telemetry.change.source", source),
+			zap.String("sampler.previous", previous),
+			zap.String("sampler", c.sampler.Description()),
+		)
+	}
+
+	if update.LogLevel != nil {
+		previous := c.level.Level()
+		c.level.SetLevel(*update.LogLevel)
+		audit("Log level changed",
+			zap.String("telemetry.change.source", source),
+			zap.Stringer("log.level.previous", previous),
+			zap.Stringer("log.level", *update.LogLevel),
+		)
+	}
+
+	c.updatedAt = time.Now()
+	c.updatedBy = source
+	return nil
+}
+
+// audit logs a runtime change at info level through the global logger,
+// bypassing its level so the change is recorded whatever the level is.
+func audit(msg string, fields ...zap.Field) {
+	entry := zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now(), Message: msg}
+	if err := zap.L().Core().Write(entry, fields); err != nil {
+		otel.Handle(err)
+	}
+}
+
+func (c *runtimeControl) status() RuntimeStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := RuntimeStatus{
+		Sampler:   c.sampler.Description(),
+		UpdatedBy: c.updatedBy,
+	}
+	if c.sampling != nil {
+		sampling := *c.sampling
+		status.Sampling = &sampling
+	}
+	if c.level != nil {
+		status.LogLevel = c.level.Level().String()
+	}
+	if !c.updatedAt.IsZero() {
+		updatedAt := c.updatedAt
+		status.UpdatedAt = &updatedAt
+	}
+	return status
+}
+
+// UpdateRuntimeConfig applies update to the SDK set up by SetupOTelSDK and
+// logs the change together with source, e.g. the address of the admin
+// client. Nothing is changed when update is invalid.
+func UpdateRuntimeConfig(source string, update RuntimeConfig) error {
+	control := loadState().runtime
+	if control == nil {
+		return errNotSetUp
+	}
+	return control.update(source, update)
+}
+
+// LoadRuntimeConfig reads a RuntimeConfig from the JSON file at name, e.g.
+//
+//	{"sampling": {"ratio": 0.1, "rules": [{"route": "/client/notifications/email", "ratio": 1}]}, "log_level": "info"}
+func LoadRuntimeConfig(name string) (RuntimeConfig, error) {
+	var cfg RuntimeConfig
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("telemetry: invalid runtime config %s: %w", name, err)
+	}
+	return cfg, nil
+}
+
+// ReloadOnSIGHUP applies the RuntimeConfig found in the file at name each
+// time the process receives SIGHUP, until ctx is done.
+func ReloadOnSIGHUP(ctx context.Context, name string) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+			}
+
+			cfg, err := LoadRuntimeConfig(name)
+			if err == nil {
+				err = UpdateRuntimeConfig("SIGHUP "+name, cfg)
+			}
+			if err != nil {
+				zap.L().Error("Cannot reload telemetry runtime config", zap.String("file.path", name), zap.Error(err))
+			}
+		}
+	}()
+}
+
+// AdminHandler returns the handler of the admin API. GET returns the
+// RuntimeStatus; PUT applies the RuntimeConfig in the request body and
+// returns the new RuntimeStatus. It changes the behaviour of the process
+// and must only be served to operators, e.g. on a loopback address.
+func AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		control := loadState().runtime
+		if control == nil {
+			http.Error(w, errNotSetUp.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var update RuntimeConfig
+			dec := json.NewDecoder(r.Body)
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&update); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := control.update("admin API "+r.RemoteAddr, update); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(control.status())
+	})
+}
@@ -0,0 +1,270 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestAdminHandler(t *testing.T) {
+	t.Run("not set up", func(t *testing.T) {
+		rec := serveAdmin(http.MethodGet, "")
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("status = %d, want 503", rec.Code)
+		}
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{name: "get", method: http.MethodGet, wantStatus: http.StatusOK},
+		{name: "empty update", method: http.MethodPut, body: `{}`, wantStatus: http.StatusOK},
+		{
+			name:       "sampling and log level",
+			method:     http.MethodPut,
+			body:       `{"sampling": {"ratio": 0.5, "rules": [{"route": "/client/*", "ratio": 1}]}, "log_level": "debug"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "ratio out of range",
+			method:     http.MethodPut,
+			body:       `{"sampling": {"ratio": 1.5}}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "sampling ratio 1.5 is not between 0 and 1",
+		},
+		{
+			name:       "rule ratio out of range",
+			method:     http.MethodPut,
+			body:       `{"sampling": {"ratio": 1, "rules": [{"route": "/client/*", "ratio": -1}]}}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  `sampling ratio -1 of route "/client/*" is not between 0 and 1`,
+		},
+		{
+			name:       "invalid route",
+			method:     http.MethodPut,
+			body:       `{"sampling": {"ratio": 1, "rules": [{"route": "/client/[", "ratio": 1}]}}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  `invalid route pattern "/client/["`,
+		},
+		{
+			name:       "invalid log level",
+			method:     http.MethodPut,
+			body:       `{"log_level": "loud"}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "unrecognized level",
+		},
+		{
+			name:       "unknown field",
+			method:     http.MethodPut,
+			body:       `{"ratio": 1}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  `unknown field "ratio"`,
+		},
+		{
+			name:       "method not allowed",
+			method:     http.MethodDelete,
+			wantStatus: http.StatusMethodNotAllowed,
+			wantError:  "Method Not Allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+			setupRuntime(t, WithLogLevel(level))
+			before := CurrentStatus().Runtime
+
+			rec := serveAdmin(tt.method, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantError != "" {
+				if !strings.Contains(rec.Body.String(), tt.wantError) {
+					t.Errorf("body = %q, want %q", rec.Body, tt.wantError)
+				}
+				// A rejected update changes nothing.
+				if after := CurrentStatus().Runtime; after.Sampler != before.Sampler || after.LogLevel != before.LogLevel || after.UpdatedAt != nil {
+					t.Errorf("runtime = %+v after a rejected update, want %+v", after, before)
+				}
+				return
+			}
+
+			var status RuntimeStatus
+			if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if status.Sampler != CurrentStatus().Runtime.Sampler {
+				t.Errorf("sampler = %q, want the sampler in use", status.Sampler)
+			}
+			if tt.name == "sampling and log level" {
+				if status.Sampling == nil || status.Sampling.Ratio != 0.5 || len(status.Sampling.Rules) != 1 {
+					t.Errorf("sampling = %+v, want the update", status.Sampling)
+				}
+				if status.LogLevel != "debug" || level.Level() != zapcore.DebugLevel {
+					t.Errorf("log level = %s (%s), want debug", status.LogLevel, level.Level())
+				}
+				if status.UpdatedAt == nil || !strings.HasPrefix(status.UpdatedBy, "admin API ") {
+					t.Errorf("updated = %v by %q, want the admin API", status.UpdatedAt, status.UpdatedBy)
+				}
+			}
+		})
+	}
+}
+
+func TestAdminHandlerLogLevelNotAdjustable(t *testing.T) {
+	setupRuntime(t)
+
+	rec := serveAdmin(http.MethodPut, `{"log_level": "debug"}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "log level is not adjustable") {
+		t.Errorf("response = %d %q, want 400 log level is not adjustable", rec.Code, rec.Body)
+	}
+}
+
+func TestRuntimeSamplingKeepsSetupRules(t *testing.T) {
+	setupRuntime(t,
+		WithSampler(trace.AlwaysSample()),
+		WithSamplingRules(SamplingRule{SpanName: "/healthz", Sampler: trace.NeverSample()}),
+	)
+
+	tests := []struct {
+		name     string
+		sampling SamplingConfig
+		want     map[string]bool
+	}{
+		{
+			name:     "ratio 1",
+			sampling: SamplingConfig{Ratio: 1},
+			want:     map[string]bool{"/healthz": false, "/client/notifications/push": true},
+		},
+		{
+			name:     "ratio 0 with a route rule",
+			sampling: SamplingConfig{Ratio: 0, Rules: []RouteSamplingRule{{Route: "/client/*/email", Ratio: 1}}},
+			want: map[string]bool{
+				"/healthz":                    false,
+				"/client/notifications/email": true,
+				"/client/notifications/push":  false,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := UpdateRuntimeConfig("test", RuntimeConfig{Sampling: &tt.sampling}); err != nil {
+				t.Fatalf("update: %v", err)
+			}
+			for name, want := range tt.want {
+				_, span := otel.Tracer("runtime-test").Start(context.Background(), name)
+				span.End()
+				if got := span.SpanContext().IsSampled(); got != want {
+					t.Errorf("%s sampled = %t, want %t", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadRuntimeConfig(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	writeFile(t, valid, `{"sampling": {"ratio": 0.1, "rules": [{"route": "/client/notifications/email", "ratio": 1}]}, "log_level": "warn"}`)
+	invalid := filepath.Join(dir, "invalid.json")
+	writeFile(t, invalid, `{"sampling": {"ratio": "all"}}`)
+
+	cfg, err := LoadRuntimeConfig(valid)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Sampling == nil || cfg.Sampling.Ratio != 0.1 || len(cfg.Sampling.Rules) != 1 || cfg.Sampling.Rules[0].Ratio != 1 {
+		t.Errorf("sampling = %+v", cfg.Sampling)
+	}
+	if cfg.LogLevel == nil || *cfg.LogLevel != zapcore.WarnLevel {
+		t.Errorf("log level = %v, want warn", cfg.LogLevel)
+	}
+
+	if _, err := LoadRuntimeConfig(invalid); err == nil || !strings.Contains(err.Error(), "invalid runtime config") {
+		t.Errorf("load invalid = %v, want invalid runtime config", err)
+	}
+	if _, err := LoadRuntimeConfig(filepath.Join(dir, "missing.json")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("load missing = %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestReloadOnSIGHUP(t *testing.T) {
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	setupRuntime(t, WithLogLevel(level))
+
+	name := filepath.Join(t.TempDir(), "runtime.json")
+	writeFile(t, name, `{"sampling": {"ratio": 0.25}, "log_level": "debug"}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	ReloadOnSIGHUP(ctx, name)
+
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("find process: %v", err)
+	}
+	if err := process.Signal(syscall.SIGHUP); err != nil {
+		t.Fatalf("signal: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for level.Level() != zapcore.DebugLevel {
+		if time.Now().After(deadline) {
+			t.Fatalf("log level = %s after SIGHUP, want debug", level.Level())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	runtime := CurrentStatus().Runtime
+	if runtime.Sampling == nil || runtime.Sampling.Ratio != 0.25 || runtime.UpdatedBy != "SIGHUP "+name {
+		t.Errorf("runtime = %+v, want the sampling reloaded by SIGHUP", runtime)
+	}
+}
+
+// setupRuntime sets the SDK up without exporters until the test ends.
+func setupRuntime(t *testing.T, opts ...Option) {
+	t.Helper()
+
+	opts = append([]Option{
+		WithTraceExporter(ExporterNone),
+		WithMetricExporter(ExporterNone),
+		WithLogExporter(ExporterNone),
+	}, opts...)
+	shutdown, err := SetupOTelSDK(context.Background(), "runtime-test", opts...)
+	if err != nil {
+		t.Fatalf("SetupOTelSDK: %v", err)
+	}
+	t.Cleanup(func() { _ = shutdown(context.Background()) })
+}
+
+func serveAdmin(method, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	AdminHandler().ServeHTTP(rec, httptest.NewRequest(method, "/admin/telemetry", strings.NewReader(body)))
+	return rec
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
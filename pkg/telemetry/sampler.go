@@ -0,0 +1,210 @@
+package telemetry
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	envTracesSampler    = "OTEL_TRACES_SAMPLER"
+	envTracesSamplerArg = "OTEL_TRACES_SAMPLER_ARG"
+
+	defaultSamplerRatio     = 1.0
+	defaultSamplerRateLimit = 100.0
+)
+
+// samplerFromEnv builds the sampler named by OTEL_TRACES_SAMPLER. Besides the
+// values defined by the specification it accepts "ratelimiting" and
+// "parentbased_ratelimiting", taking the traces per second as argument.
+// A nil sampler is returned when the variable is not set.
+func samplerFromEnv() (trace.Sampler, error) {
+	name := strings.ToLower(strings.TrimSpace(os.Getenv(envTracesSampler)))
+	if name == "" {
+		return nil, nil
+	}
+	arg := strings.TrimSpace(os.Getenv(envTracesSamplerArg))
+
+	switch name {
+	case "always_on":
+		return trace.AlwaysSample(), nil
+	case "always_off":
+		return trace.NeverSample(), nil
+	case "traceidratio":
+		ratio, err := samplerArg(arg, defaultSamplerRatio)
+		if err != nil {
+			return nil, err
+		}
+		return trace.TraceIDRatioBased(ratio), nil
+	case "ratelimiting":
+		limit, err := samplerArg(arg, defaultSamplerRateLimit)
+		if err != nil {
+			return nil, err
+		}
+		return RateLimitingSampler(limit), nil
+	case "parentbased_always_on":
+		return trace.ParentBased(trace.AlwaysSample()), nil
+	case "parentbased_always_off":
+		return trace.ParentBased(trace.NeverSample()), nil
+	case "parentbased_traceidratio":
+		ratio, err := samplerArg(arg, defaultSamplerRatio)
+		if err != nil {
+			return nil, err
+		}
+		return trace.ParentBased(trace.TraceIDRatioBased(ratio)), nil
+	case "parentbased_ratelimiting":
+		limit, err := samplerArg(arg, defaultSamplerRateLimit)
+		if err != nil {
+			return nil, err
+		}
+		return trace.ParentBased(RateLimitingSampler(limit)), nil
+	default:
+		return nil, fmt.Errorf("telemetry: unsupported %s value %q", envTracesSampler, name)
+	}
+}
+
+func samplerArg(arg string, fallback float64) (float64, error) {
+	if arg == "" {
+		return fallback, nil
+	}
+	v, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return 0, fmt.Errorf("telemetry: invalid %s value %q: %w", envTracesSamplerArg, arg, err)
+	}
+	return v, nil
+}
+
+// ParentBasedRatio samples root spans by trace ID ratio and follows the
+// decision of the parent otherwise.
+func ParentBasedRatio(ratio float64) trace.Sampler {
+	return trace.ParentBased(trace.TraceIDRatioBased(ratio))
+}
+
+// RateLimitingSampler samples at most perSecond traces per second, allowing
+// bursts of up to one second worth of traces. A rate of zero or less samples
+// nothing.
+func RateLimitingSampler(perSecond float64) trace.Sampler {
+	if perSecond <= 0 {
+		return trace.NeverSample()
+	}
+	return &rateLimitingSampler{
+		rate:    perSecond,
+		balance: max(perSecond, 1),
+		last:    time.Now(),
+		now:     time.Now,
+	}
+}
+
+type rateLimitingSampler struct {
+	mu      sync.Mutex
+	rate    float64
+	balance float64
+	last    time.Time
+	now     func() time.Time
+}
+
+func (s *rateLimitingSampler) ShouldSample(p trace.SamplingParameters) trace.SamplingResult {
+	decision := trace.Drop
+	if s.take() {
+		decision = trace.RecordAndSample
+	}
+	return trace.SamplingResult{
+		Decision:   decision,
+		Tracestate: oteltrace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+}
+
+func (s *rateLimitingSampler) take() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.balance = min(s.balance+now.Sub(s.last).Seconds()*s.rate, max(s.rate, 1))
+	s.last = now
+
+	if s.balance < 1 {
+		return false
+	}
+	s.balance--
+	return true
+}
+
+func (s *rateLimitingSampler) Description() string {
+	return fmt.Sprintf("RateLimitingSampler{%g}", s.rate)
+}
+
+// SamplingRule delegates the decision for matching spans to Sampler.
+// Spans are matched on their name and on the attributes given when they are
+// started, so outcomes only known when a span ends (such as an error status)
+// must be handled by a tail sampler instead.
+type SamplingRule struct {
+	// SpanName is a path.Match pattern, e.g. "/server/notifications/*".
+	// An empty pattern matches every span.
+	SpanName string
+	// Attributes must all be present on the span with the same value.
+	Attributes []attribute.KeyValue
+	// Sampler decides on matching spans.
+	Sampler trace.Sampler
+}
+
+func (r SamplingRule) matches(p trace.SamplingParameters) bool {
+	if r.SpanName != "" {
+		if ok, _ := path.Match(r.SpanName, p.Name); !ok {
+			return false
+		}
+	}
+
+	for _, want := range r.Attributes {
+		found := false
+		for _, got := range p.Attributes {
+			if got.Key == want.Key && got.Value == want.Value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// RuleBasedSampler applies the sampler of the first matching rule and
+// falls back to fallback when no rule matches.
+func RuleBasedSampler(fallback trace.Sampler, rules ...SamplingRule) trace.Sampler {
+	return &ruleBasedSampler{
+		rules:    rules,
+		fallback: fallback,
+	}
+}
+
+type ruleBasedSampler struct {
+	rules    []SamplingRule
+	fallback trace.Sampler
+}
+
+func (s *ruleBasedSampler) ShouldSample(p trace.SamplingParameters) trace.SamplingResult {
+	for _, rule := range s.rules {
+		if rule.matches(p) {
+			return rule.Sampler.ShouldSample(p)
+		}
+	}
+	return s.fallback.ShouldSample(p)
+}
+
+func (s *ruleBasedSampler) Description() string {
+	descriptions := make([]string, 0, len(s.rules))
+	for _, rule := range s.rules {
+		descriptions = append(descriptions, fmt.Sprintf("%q:%s", rule.SpanName, rule.Sampler.Description()))
+	}
+	return fmt.Sprintf("RuleBasedSampler{rules:[%s],fallback:%s}",
+		strings.Join(descriptions, ","), s.fallback.Description())
+}
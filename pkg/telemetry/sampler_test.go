@@ -0,0 +1,202 @@
+package telemetry
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestRateLimitingSampler(t *testing.T) {
+	type step struct {
+		advance time.Duration
+		want    trace.SamplingDecision
+	}
+	tests := []struct {
+		name      string
+		perSecond float64
+		steps     []step
+	}{
+		{
+			name:      "burst of one second",
+			perSecond: 2,
+			steps: []step{
+				{0, trace.RecordAndSample},
+				{0, trace.RecordAndSample},
+				{0, trace.Drop},
+			},
+		},
+		{
+			name:      "refill at rate",
+			perSecond: 2,
+			steps: []step{
+				{0, trace.RecordAndSample},
+				{0, trace.RecordAndSample},
+				{250 * time.Millisecond, trace.Drop},
+				{250 * time.Millisecond, trace.RecordAndSample},
+				{0, trace.Drop},
+			},
+		},
+		{
+			name:      "balance capped at burst",
+			perSecond: 2,
+			steps: []step{
+				{time.Minute, trace.RecordAndSample},
+				{0, trace.RecordAndSample},
+				{0, trace.Drop},
+			},
+		},
+		{
+			name:      "less than one per second",
+			perSecond: 0.5,
+			steps: []step{
+				{0, trace.RecordAndSample},
+				{time.Second, trace.Drop},
+				{time.Second, trace.RecordAndSample},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			sampler := RateLimitingSampler(tt.perSecond).(*rateLimitingSampler)
+			sampler.last = now
+			sampler.now = func() time.Time { return now }
+
+			for i, step := range tt.steps {
+				now = now.Add(step.advance)
+				got := sampler.ShouldSample(trace.SamplingParameters{Name: "span"}).Decision
+				if got != step.want {
+					t.Errorf("step %d: decision = %v, want %v", i, got, step.want)
+				}
+			}
+		})
+	}
+
+	for _, perSecond := range []float64{0, -1} {
+		sampler := RateLimitingSampler(perSecond)
+		if got := sampler.ShouldSample(trace.SamplingParameters{Name: "span"}).Decision; got != trace.Drop {
+			t.Errorf("RateLimitingSampler(%v) decision = %v, want %v", perSecond, got, trace.Drop)
+		}
+	}
+}
+
+func TestRuleBasedSampler(t *testing.T) {
+	sampler := RuleBasedSampler(fixedSampler(trace.RecordOnly),
+		SamplingRule{
+			SpanName:   "/server/notifications/*",
+			Attributes: []attribute.KeyValue{attribute.String("channel", "email")},
+			Sampler:    trace.AlwaysSample(),
+		},
+		SamplingRule{SpanName: "/server/notifications/*", Sampler: trace.NeverSample()},
+		SamplingRule{Attributes: []attribute.KeyValue{attribute.Bool("debug", true)}, Sampler: trace.AlwaysSample()},
+	)
+
+	tests := []struct {
+		name  string
+		span  string
+		attrs []attribute.KeyValue
+		want  trace.SamplingDecision
+	}{
+		{
+			name:  "first matching rule wins",
+			span:  "/server/notifications/email",
+			attrs: []attribute.KeyValue{attribute.String("channel", "email"), attribute.Bool("debug", true)},
+			want:  trace.RecordAndSample,
+		},
+		{
+			name:  "attribute value differs",
+			span:  "/server/notifications/push",
+			attrs: []attribute.KeyValue{attribute.String("channel", "push")},
+			want:  trace.Drop,
+		},
+		{
+			name: "attribute missing",
+			span: "/server/notifications/email",
+			want: trace.Drop,
+		},
+		{
+			name:  "empty pattern matches any name",
+			span:  "controller:PushNotification",
+			attrs: []attribute.KeyValue{attribute.Bool("debug", true)},
+			want:  trace.RecordAndSample,
+		},
+		{
+			name: "wildcard does not cross slash",
+			span: "/server/notifications/email/retry",
+			want: trace.RecordOnly,
+		},
+		{
+			name:  "fallback",
+			span:  "controller:PushNotification",
+			attrs: []attribute.KeyValue{attribute.Bool("debug", false)},
+			want:  trace.RecordOnly,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sampler.ShouldSample(trace.SamplingParameters{Name: tt.span, Attributes: tt.attrs}).Decision
+			if got != tt.want {
+				t.Errorf("decision = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fixedSampler always returns its decision.
+type fixedSampler trace.SamplingDecision
+
+func (s fixedSampler) ShouldSample(trace.SamplingParameters) trace.SamplingResult {
+	return trace.SamplingResult{Decision: trace.SamplingDecision(s)}
+}
+
+func (s fixedSampler) Description() string {
+	return "fixedSampler"
+}
+
+func TestSamplerFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		sampler string
+		arg     string
+		// want is a prefix of the sampler description.
+		want    string
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "ratelimiting default", sampler: "ratelimiting", want: "RateLimitingSampler{100}"},
+		{name: "ratelimiting", sampler: "ratelimiting", arg: "5", want: "RateLimitingSampler{5}"},
+		{name: "case and spaces", sampler: " RateLimiting ", arg: " 2.5 ", want: "RateLimitingSampler{2.5}"},
+		{
+			name:    "parentbased ratelimiting",
+			sampler: "parentbased_ratelimiting",
+			arg:     "5",
+			want:    "ParentBased{root:RateLimitingSampler{5},",
+		},
+		{name: "ratelimiting invalid argument", sampler: "ratelimiting", arg: "fast", wantErr: true},
+		{name: "parentbased ratelimiting invalid argument", sampler: "parentbased_ratelimiting", arg: "fast", wantErr: true},
+		{name: "unsupported", sampler: "jaeger_remote", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envTracesSampler, tt.sampler)
+			t.Setenv(envTracesSamplerArg, tt.arg)
+
+			sampler, err := samplerFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %t", err, tt.wantErr)
+			}
+			switch {
+			case tt.want == "" && sampler != nil:
+				t.Errorf("sampler = %s, want nil", sampler.Description())
+			case tt.want != "" && (sampler == nil || !strings.HasPrefix(sampler.Description(), tt.want)):
+				t.Errorf("sampler = %v, want %s...", sampler, tt.want)
+			}
+		})
+	}
+}
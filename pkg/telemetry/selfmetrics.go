@@ -0,0 +1,358 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Signals reported on the exporter metrics and in Status.
+const (
+	signalTraces  = "traces"
+	signalMetrics = "metrics"
+	signalLogs    = "logs"
+)
+
+// selfMetrics observes the pipeline set up by one SetupOTelSDK call: the
+// health of every exporter, the span queue and the errors reported to
+// otel.Handle.
+type selfMetrics struct {
+	errors    *errorHandler
+	exporters []*exporterHealth
+	queue     *spanQueue
+	// tailSampling creates its instruments in start, like m.
+	tailSampling *tailSamplingProcessor
+
+	// instruments is set once the meter provider exists, which is after
+	// the exporters of the tracer provider started.
+	instruments atomic.Pointer[selfInstruments]
+}
+
+type selfInstruments struct {
+	exportDuration otelmetric.Float64Histogram
+	spansExported  otelmetric.Int64Counter
+	spansDropped   otelmetric.Int64Counter
+	// queueRegistration observes the span queue until it is shut down.
+	queueRegistration otelmetric.Registration
+}
+
+func newSelfMetrics(cfg *config) *selfMetrics {
+	return &selfMetrics{errors: newErrorHandler(cfg.errorLogBurst, cfg.errorLogInterval)}
+}
+
+// start creates the instruments of m from provider.
+func (m *selfMetrics) start(provider otelmetric.MeterProvider) error {
+	meter := provider.Meter(instrumentationName)
+
+	var inst selfInstruments
+	var err error
+	inst.exportDuration, err = meter.Float64Histogram("telemetry.exporter.duration",
+		otelmetric.WithDescription("Duration of telemetry exports."),
+		otelmetric.WithUnit("s"))
+	if err != nil {
+		return err
+	}
+
+	inst.spansExported, err = meter.Int64Counter("telemetry.spans.exported",
+		otelmetric.WithDescription("Spans passed to the trace exporter, with error.type set when the export failed."),
+		otelmetric.WithUnit("{span}"))
+	if err != nil {
+		return err
+	}
+
+	inst.spansDropped, err = meter.Int64Counter("telemetry.spans.dropped",
+		otelmetric.WithDescription("Spans dropped because the export queue was full or shut down before their export."),
+		otelmetric.WithUnit("{span}"))
+	if err != nil {
+		return err
+	}
+
+	if m.queue != nil {
+		size, err := meter.Int64ObservableUpDownCounter("telemetry.span_queue.size",
+			otelmetric.WithDescription("Spans waiting to be exported."),
+			otelmetric.WithUnit("{span}"))
+		if err != nil {
+			return err
+		}
+
+		capacity, err := meter.Int64ObservableUpDownCounter("telemetry.span_queue.capacity",
+			otelmetric.WithDescription("Spans the export queue can hold."),
+			otelmetric.WithUnit("{span}"))
+		if err != nil {
+			return err
+		}
+
+		inst.queueRegistration, err = meter.RegisterCallback(func(_ context.Context, o otelmetric.Observer) error {
+			o.ObserveInt64(size, m.queue.depth())
+			o.ObserveInt64(capacity, m.queue.capacity)
+			return nil
+		}, size, capacity)
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.tailSampling != nil {
+		if err := m.tailSampling.start(provider); err != nil {
+			return err
+		}
+	}
+
+	m.instruments.Store(&inst)
+	return nil
+}
+
+// exporter starts tracking the health of the exporter of signal.
+func (m *selfMetrics) exporter(signal string, kind Exporter) *exporterHealth {
+	health := &exporterHealth{
+		metrics:  m,
+		signal:   signal,
+		exporter: kind,
+		attrs: []attribute.KeyValue{
+			attribute.String("telemetry.signal", signal),
+			attribute.String("telemetry.exporter", string(kind)),
+		},
+	}
+	m.exporters = append(m.exporters, health)
+	return health
+}
+
+// newBatchSpanProcessor returns the batch span processor exporting to
+// exporter, with its queue and exports observed by m.
+func (m *selfMetrics) newBatchSpanProcessor(exporter trace.SpanExporter, kind Exporter, cfg *config) trace.SpanProcessor {
+	m.queue = &spanQueue{metrics: m, capacity: int64(cfg.maxQueueSize)}
+	m.queue.next = trace.NewBatchSpanProcessor(
+		&spanExporter{next: exporter, health: m.exporter(signalTraces, kind), queue: m.queue},
+		trace.WithBatchTimeout(cfg.batchTimeout),
+		trace.WithExportTimeout(cfg.exportTimeout),
+		trace.WithMaxQueueSize(cfg.maxQueueSize),
+		trace.WithMaxExportBatchSize(cfg.maxExportBatchSize),
+	)
+	return m.queue
+}
+
+// wrapMetricExporter observes the exports of exporter.
+func (m *selfMetrics) wrapMetricExporter(exporter metric.Exporter, kind Exporter) metric.Exporter {
+	return &metricExporter{Exporter: exporter, health: m.exporter(signalMetrics, kind)}
+}
+
+// wrapLogExporter observes the exports of exporter.
+func (m *selfMetrics) wrapLogExporter(exporter log.Exporter, kind Exporter) log.Exporter {
+	return &logExporter{Exporter: exporter, health: m.exporter(signalLogs, kind)}
+}
+
+// exporterHealth tracks the outcome of the exports of one signal.
+type exporterHealth struct {
+	metrics  *selfMetrics
+	signal   string
+	exporter Exporter
+	attrs    []attribute.KeyValue
+
+	mu                  sync.Mutex
+	exports             uint64
+	failures            uint64
+	consecutiveFailures int
+	failingSince        time.Time
+	lastSuccess         time.Time
+	lastFailure         time.Time
+	lastError           string
+}
+
+// observe records an export started at begin which returned err, and
+// returns the attributes describing its outcome.
+func (h *exporterHealth) observe(ctx context.Context, begin time.Time, err error) otelmetric.MeasurementOption {
+	now := time.Now()
+
+	h.mu.Lock()
+	h.exports++
+	if err != nil {
+		h.failures++
+		if h.consecutiveFailures == 0 {
+			h.failingSince = now
+		}
+		h.consecutiveFailures++
+		h.lastFailure = now
+		h.lastError = err.Error()
+	} else {
+		h.consecutiveFailures = 0
+		h.lastSuccess = now
+	}
+	h.mu.Unlock()
+
+	attrs := h.attrs
+	if err != nil {
+		attrs = append(attrs[:len(attrs):len(attrs)], exportErrorType(err))
+	}
+	opt := otelmetric.WithAttributes(attrs...)
+	if inst := h.metrics.instruments.Load(); inst != nil {
+		inst.exportDuration.Record(ctx, now.Sub(begin).Seconds(), opt)
+	}
+	return opt
+}
+
+func (h *exporterHealth) status() ExporterStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	status := ExporterStatus{
+		Signal:              h.signal,
+		Exporter:            h.exporter,
+		Healthy:             h.consecutiveFailures == 0,
+		Exports:             h.exports,
+		Failures:            h.failures,
+		ConsecutiveFailures: h.consecutiveFailures,
+		LastError:           h.lastError,
+	}
+	if !h.lastSuccess.IsZero() {
+		lastSuccess := h.lastSuccess
+		status.LastSuccess = &lastSuccess
+	}
+	if !h.lastFailure.IsZero() {
+		lastFailure := h.lastFailure
+		status.LastFailure = &lastFailure
+	}
+	if h.consecutiveFailures > 0 {
+		failingSince := h.failingSince
+		status.FailingSince = &failingSince
+	}
+	return status
+}
+
+// exportErrorType returns the error.type of a failed export, kept to a few
+// values to bound the metric cardinality.
+func exportErrorType(err error) attribute.KeyValue {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return semconv.ErrorTypeKey.String("timeout")
+	case errors.Is(err, context.Canceled):
+		return semconv.ErrorTypeKey.String("canceled")
+	default:
+		return semconv.ErrorTypeOther
+	}
+}
+
+// spanQueue sits in front of the batch span processor and counts the spans
+// from the moment they end until their export returns. Spans ending while
+// the queue is full are dropped and counted here, the batch processor would
+// otherwise drop them silently, as are the spans left when it shuts down.
+type spanQueue struct {
+	next     trace.SpanProcessor
+	metrics  *selfMetrics
+	capacity int64
+	// The queue holds the spans enqueued but neither exported nor dropped.
+	enqueued atomic.Int64
+	exported atomic.Int64
+	dropped  atomic.Int64
+	stopped  atomic.Bool
+}
+
+// depth returns the number of spans waiting to be exported.
+func (q *spanQueue) depth() int64 {
+	return max(q.enqueued.Load()-q.exported.Load()-q.dropped.Load(), 0)
+}
+
+// drop counts n spans that will not be exported.
+func (q *spanQueue) drop(n int64) {
+	q.dropped.Add(n)
+	if inst := q.metrics.instruments.Load(); inst != nil {
+		inst.spansDropped.Add(context.Background(), n)
+	}
+}
+
+func (q *spanQueue) OnStart(parent context.Context, s trace.ReadWriteSpan) {
+	q.next.OnStart(parent, s)
+}
+
+func (q *spanQueue) OnEnd(s trace.ReadOnlySpan) {
+	// The batch processor ignores spans that are not sampled or end after
+	// its shutdown.
+	if !s.SpanContext().IsSampled() || q.stopped.Load() {
+		return
+	}
+
+	q.enqueued.Add(1)
+	if q.depth() > q.capacity {
+		q.drop(1)
+		return
+	}
+	q.next.OnEnd(s)
+}
+
+// Shutdown counts the spans the batch processor did not export before it
+// returned as dropped, so the queue is left empty.
+func (q *spanQueue) Shutdown(ctx context.Context) error {
+	err := q.next.Shutdown(ctx)
+	if q.stopped.CompareAndSwap(false, true) {
+		if lost := q.depth(); lost > 0 {
+			q.drop(lost)
+		}
+		if inst := q.metrics.instruments.Load(); inst != nil && inst.queueRegistration != nil {
+			err = errors.Join(err, inst.queueRegistration.Unregister())
+		}
+	}
+	return err
+}
+
+func (q *spanQueue) ForceFlush(ctx context.Context) error {
+	return q.next.ForceFlush(ctx)
+}
+
+type spanExporter struct {
+	next   trace.SpanExporter
+	health *exporterHealth
+	queue  *spanQueue
+}
+
+func (e *spanExporter) ExportSpans(ctx context.Context, spans []trace.ReadOnlySpan) error {
+	begin := time.Now()
+	err := e.next.ExportSpans(ctx, spans)
+	if !e.queue.stopped.Load() {
+		// Spans still exporting once the queue stopped are counted as
+		// dropped already.
+		e.queue.exported.Add(int64(len(spans)))
+	}
+
+	opt := e.health.observe(ctx, begin, err)
+	if inst := e.health.metrics.instruments.Load(); inst != nil {
+		inst.spansExported.Add(ctx, int64(len(spans)), opt)
+	}
+	return err
+}
+
+func (e *spanExporter) Shutdown(ctx context.Context) error {
+	return e.next.Shutdown(ctx)
+}
+
+type metricExporter struct {
+	metric.Exporter
+	health *exporterHealth
+}
+
+func (e *metricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	begin := time.Now()
+	err := e.Exporter.Export(ctx, rm)
+	e.health.observe(ctx, begin, err)
+	return err
+}
+
+type logExporter struct {
+	log.Exporter
+	health *exporterHealth
+}
+
+func (e *logExporter) Export(ctx context.Context, records []log.Record) error {
+	begin := time.Now()
+	err := e.Exporter.Export(ctx, records)
+	e.health.observe(ctx, begin, err)
+	return err
+}
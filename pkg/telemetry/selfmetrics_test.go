@@ -0,0 +1,81 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestSpanQueue(t *testing.T) {
+	cfg := newConfig(WithBatchTimeout(time.Hour), WithMaxQueueSize(2))
+	m := newSelfMetrics(cfg)
+	exporter := &blockingExporter{release: make(chan struct{})}
+	provider := trace.NewTracerProvider(
+		trace.WithSampler(trace.AlwaysSample()),
+		trace.WithSpanProcessor(m.newBatchSpanProcessor(exporter, ExporterOTLPHTTP, cfg)),
+	)
+	reader := sdkmetric.NewManualReader()
+	if err := m.start(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	tracer := provider.Tracer("queue-test")
+	for range 3 {
+		_, span := tracer.Start(context.Background(), "queued")
+		span.End()
+	}
+	if size, dropped := m.queue.depth(), m.queue.dropped.Load(); size != 2 || dropped != 1 {
+		t.Errorf("queue = %d spans, %d dropped, want 2 spans and 1 dropped when full", size, dropped)
+	}
+	if size := observedQueueSize(t, reader); size != 2 {
+		t.Errorf("telemetry.span_queue.size = %d, want 2", size)
+	}
+
+	// The export blocks past the shutdown deadline, leaving the spans
+	// unexported.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := provider.Shutdown(ctx); err == nil {
+		t.Errorf("shutdown = nil, want the deadline error")
+	}
+	close(exporter.release)
+
+	if size, dropped := m.queue.depth(), m.queue.dropped.Load(); size != 0 || dropped != 3 {
+		t.Errorf("queue = %d spans, %d dropped after shutdown, want none and 3 dropped", size, dropped)
+	}
+	if size := observedQueueSize(t, reader); size != -1 {
+		t.Errorf("telemetry.span_queue.size = %d after shutdown, want it unregistered", size)
+	}
+}
+
+// blockingExporter blocks every export until release is closed.
+type blockingExporter struct {
+	release chan struct{}
+}
+
+func (e *blockingExporter) ExportSpans(context.Context, []trace.ReadOnlySpan) error {
+	<-e.release
+	return nil
+}
+
+func (e *blockingExporter) Shutdown(context.Context) error { return nil }
+
+// observedQueueSize collects telemetry.span_queue.size from reader, or -1
+// when it is not observed.
+func observedQueueSize(t *testing.T, reader *sdkmetric.ManualReader) int64 {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	size, _ := findMetric(rm, "telemetry.span_queue.size").Data.(metricdata.Sum[int64])
+	if len(size.DataPoints) == 0 {
+		return -1
+	}
+	return size.DataPoints[0].Value
+}
@@ -3,40 +3,70 @@ package telemetry
 import (
 	"context"
 	"errors"
-	"time"
+	"net/http"
+	"sync/atomic"
 
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
-	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
-	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/log/global"
+	"go.opentelemetry.io/otel/sdk/log"
 	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
-	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
-var (
-	tracer oteltrace.Tracer
-)
+// sdkState is the package state of the SDK set up by the latest SetupOTelSDK
+// call. It is replaced as a whole, so it can be read while the SDK is set up
+// again, e.g. from tests.
+type sdkState struct {
+	// self observes the pipeline.
+	self *selfMetrics
+	// runtime holds the settings that can be changed while the process runs.
+	runtime *runtimeControl
+	// prometheus serves the scrape endpoint once the Prometheus reader is
+	// registered.
+	prometheus http.Handler
+	// redactor redacts zap fields once redaction rules are configured.
+	redactor *redactor
+	// loggerBaggage selects the baggage members added to Logger fields.
+	loggerBaggage *baggagePromoter
+	// hashKey keys HashValue when configured.
+	hashKey []byte
+}
+
+var installed atomic.Pointer[sdkState]
 
-// StartSpan starting a new span from or with context
-func StartSpan(ctx context.Context, spanName string) (context.Context, oteltrace.Span) {
-	return tracer.Start(ctx, spanName)
+// loadState returns the state of the SDK set up, or the zero state before
+// SetupOTelSDK.
+func loadState() *sdkState {
+	if state := installed.Load(); state != nil {
+		return state
+	}
+	return &sdkState{}
 }
 
 // SetupOTelSDK bootstraps the OpenTelemetry pipeline.
 // If it does not return an error, make sure to call shutdown for proper cleanup.
-func SetupOTelSDK(ctx context.Context, serviceName string) (shutdown func(context.Context) error, err error) {
+func SetupOTelSDK(ctx context.Context, serviceName string, opts ...Option) (shutdown func(context.Context) error, err error) {
+	cfg := newConfig(opts...)
+
 	var shutdownFuncs []func(context.Context) error
+	restore := saveGlobals()
 
 	// shutdown calls cleanup functions registered via shutdownFuncs.
 	// The errors from the calls are joined.
 	// Each registered cleanup will be invoked once.
+	// The global state found before setup is restored afterwards, so the SDK
+	// can be set up again, e.g. from tests.
 	shutdown = func(ctx context.Context) error {
 		var err error
 		for _, fn := range shutdownFuncs {
 			err = errors.Join(err, fn(ctx))
 		}
 		shutdownFuncs = nil
+		if restore != nil {
+			restore()
+			restore = nil
+		}
 		return err
 	}
 
@@ -45,12 +75,43 @@ func SetupOTelSDK(ctx context.Context, serviceName string) (shutdown func(contex
 		err = errors.Join(inErr, shutdown(ctx))
 	}
 
+	// Route errors reported to otel.Handle to zap and observe the exporters.
+	self := newSelfMetrics(cfg)
+	otel.SetErrorHandler(self.errors)
+	state := &sdkState{self: self, hashKey: cfg.hashKey}
+
 	// Set up propagator.
-	prop := newPropagator()
+	prop, err := newPropagator(cfg)
+	if err != nil {
+		handleErr(err)
+		return
+	}
 	otel.SetTextMapPropagator(prop)
 
+	// Set up resource.
+	res, err := newResource(ctx, serviceName, cfg)
+	if errors.Is(err, resource.ErrPartialResource) {
+		// Some detectors failed, the remaining attributes are still usable.
+		otel.Handle(err)
+		err = nil
+	}
+	if err != nil {
+		handleErr(err)
+		return
+	}
+
+	// Set up sampler, replaceable at runtime together with the log level.
+	// The sampling rules stay in front of the sampler set at runtime.
+	sampler, err := newSampler(cfg)
+	if err != nil {
+		handleErr(err)
+		return
+	}
+	control := newRuntimeControl(sampler, cfg.samplingRules, cfg.logLevel)
+	state.runtime = control
+
 	// Set up trace provider.
-	tracerProvider, err := newTracerProvider()
+	tracerProvider, err := newTracerProvider(ctx, cfg, res, self, control.sampler)
 	if err != nil {
 		handleErr(err)
 		return
@@ -58,53 +119,243 @@ func SetupOTelSDK(ctx context.Context, serviceName string) (shutdown func(contex
 	shutdownFuncs = append(shutdownFuncs, tracerProvider.Shutdown)
 	otel.SetTracerProvider(tracerProvider)
 
-	tracer = tracerProvider.Tracer(serviceName)
+	// Set up Prometheus reader.
+	if cfg.prometheus || prometheusEnabled() {
+		reader, handler, promErr := newPrometheusReader()
+		if promErr != nil {
+			handleErr(promErr)
+			return
+		}
+		cfg.metricReaders = append(cfg.metricReaders, reader)
+		state.prometheus = handler
+	}
 
 	// Set up meter provider.
-	meterProvider, err := newMeterProvider()
+	meterProvider, err := newMeterProvider(ctx, cfg, res, self)
 	if err != nil {
 		handleErr(err)
 		return
 	}
-	shutdownFuncs = append(shutdownFuncs, meterProvider.Shutdown)
+
+	// Set up runtime and host metrics, stopped before the meter provider.
+	stopMetricGroups, err := startMetricGroups(meterProvider, cfg)
+	if err != nil {
+		handleErr(errors.Join(err, meterProvider.Shutdown(ctx)))
+		return
+	}
+	shutdownFuncs = append(shutdownFuncs, stopMetricGroups, meterProvider.Shutdown)
 	otel.SetMeterProvider(meterProvider)
+	if err := self.start(meterProvider); err != nil {
+		// The pipeline works without its own metrics.
+		otel.Handle(err)
+	}
+
+	// Set up logger provider.
+	loggerProvider, err := newLoggerProvider(ctx, cfg, res, self)
+	if err != nil {
+		handleErr(err)
+		return
+	}
+	shutdownFuncs = append(shutdownFuncs, loggerProvider.Shutdown)
+	global.SetLoggerProvider(loggerProvider)
+
+	if len(cfg.redactionRules) > 0 {
+		state.redactor = newRedactor(cfg.redactionRules)
+	}
 
+	if cfg.baggage != nil {
+		state.loggerBaggage = newBaggagePromoter(*cfg.baggage)
+	} else if len(cfg.loggerBaggageKeys) > 0 {
+		state.loggerBaggage = newBaggagePromoter(BaggageConfig{AllowedKeys: cfg.loggerBaggageKeys})
+	}
+
+	installed.Store(state)
 	return
 }
 
-func newPropagator() propagation.TextMapPropagator {
-	return propagation.NewCompositeTextMapPropagator(
-		propagation.TraceContext{},
-		propagation.Baggage{},
-	)
+// saveGlobals captures the global providers and package state replaced by
+// SetupOTelSDK and returns the function putting them back.
+func saveGlobals() func() {
+	propagator := otel.GetTextMapPropagator()
+	tracerProvider := otel.GetTracerProvider()
+	meterProvider := otel.GetMeterProvider()
+	loggerProvider := global.GetLoggerProvider()
+	errorHandler := otel.GetErrorHandler()
+	state := installed.Load()
+
+	return func() {
+		otel.SetTextMapPropagator(propagator)
+		otel.SetTracerProvider(tracerProvider)
+		otel.SetMeterProvider(meterProvider)
+		global.SetLoggerProvider(loggerProvider)
+		otel.SetErrorHandler(errorHandler)
+		installed.Store(state)
+	}
 }
 
-func newTracerProvider() (*trace.TracerProvider, error) {
-	traceExporter, err := stdouttrace.New(
-		stdouttrace.WithPrettyPrint(),
-	)
+func newTracerProvider(ctx context.Context, cfg *config, res *resource.Resource, self *selfMetrics, sampler trace.Sampler) (*trace.TracerProvider, error) {
+	kind := cfg.traceExporter
+	if kind == "" {
+		var err error
+		if kind, err = exporterFromEnv(envTracesExporter, envOTLPTracesProtocol); err != nil {
+			return nil, err
+		}
+	}
+
+	traceExporter, err := newTraceExporter(ctx, kind, cfg.fileExporter)
 	if err != nil {
 		return nil, err
 	}
 
-	tracerProvider := trace.NewTracerProvider(
-		trace.WithBatcher(traceExporter,
-			// Default is 5s.
-			trace.WithBatchTimeout(15*time.Second)),
-	)
+	opts := []trace.TracerProviderOption{
+		trace.WithResource(res),
+		trace.WithSampler(sampler),
+	}
+	if cfg.baggage != nil {
+		opts = append(opts, trace.WithSpanProcessor(NewBaggageSpanProcessor(*cfg.baggage)))
+	}
+	// The exporting processor and the registered ones share the redaction
+	// and tail sampling, so they all see the spans exported.
+	var processors spanProcessors
+	if traceExporter != nil {
+		processors = append(processors, self.newBatchSpanProcessor(traceExporter, kind, cfg))
+	}
+	processors = append(processors, cfg.spanProcessors...)
+	if len(processors) > 0 {
+		var processor trace.SpanProcessor = processors
+		if len(processors) == 1 {
+			processor = processors[0]
+		}
+		if len(cfg.redactionRules) > 0 {
+			processor = NewRedactingSpanProcessor(processor, cfg.redactionRules...)
+		}
+		if cfg.tailSampling != nil {
+			self.tailSampling = newTailSamplingProcessor(processor, *cfg.tailSampling)
+			processor = self.tailSampling
+		}
+		opts = append(opts, trace.WithSpanProcessor(processor))
+	}
+
+	tracerProvider := trace.NewTracerProvider(opts...)
 	return tracerProvider, nil
 }
 
-func newMeterProvider() (*metric.MeterProvider, error) {
-	metricExporter, err := stdoutmetric.New()
+// spanProcessors passes spans to each of its processors in turn.
+type spanProcessors []trace.SpanProcessor
+
+func (p spanProcessors) OnStart(parent context.Context, s trace.ReadWriteSpan) {
+	for _, processor := range p {
+		processor.OnStart(parent, s)
+	}
+}
+
+func (p spanProcessors) OnEnd(s trace.ReadOnlySpan) {
+	for _, processor := range p {
+		processor.OnEnd(s)
+	}
+}
+
+func (p spanProcessors) ForceFlush(ctx context.Context) error {
+	var err error
+	for _, processor := range p {
+		err = errors.Join(err, processor.ForceFlush(ctx))
+	}
+	return err
+}
+
+func (p spanProcessors) Shutdown(ctx context.Context) error {
+	var err error
+	for _, processor := range p {
+		err = errors.Join(err, processor.Shutdown(ctx))
+	}
+	return err
+}
+
+func newSampler(cfg *config) (trace.Sampler, error) {
+	sampler := cfg.sampler
+	if sampler == nil {
+		var err error
+		if sampler, err = samplerFromEnv(); err != nil {
+			return nil, err
+		}
+	}
+	if sampler == nil {
+		sampler = trace.ParentBased(trace.AlwaysSample())
+	}
+	return sampler, nil
+}
+
+func newMeterProvider(ctx context.Context, cfg *config, res *resource.Resource, self *selfMetrics) (*metric.MeterProvider, error) {
+	kind := cfg.metricExporter
+	if kind == "" {
+		var err error
+		if kind, err = exporterFromEnv(envMetricsExporter, envOTLPMetricsProtocol); err != nil {
+			return nil, err
+		}
+	}
+
+	metricExporter, err := newMetricExporter(ctx, kind, cfg.fileExporter)
 	if err != nil {
 		return nil, err
 	}
 
-	meterProvider := metric.NewMeterProvider(
-		metric.WithReader(metric.NewPeriodicReader(metricExporter,
-			// Default is 1m. Set to 3s for demonstrative purposes.
-			metric.WithInterval(3*time.Second))),
-	)
+	opts := []metric.Option{
+		metric.WithResource(res),
+		metric.WithView(hostProcessCPUTimeView),
+	}
+	// Measurements recorded within a sampled span keep its trace and span
+	// IDs as exemplars, exported over OTLP and Prometheus. The SDK applies
+	// OTEL_METRICS_EXEMPLAR_FILTER, trace_based by default, unless a filter
+	// is configured.
+	if cfg.exemplarFilter != nil {
+		opts = append(opts, metric.WithExemplarFilter(cfg.exemplarFilter))
+	}
+	if metricExporter != nil {
+		opts = append(opts, metric.WithReader(metric.NewPeriodicReader(self.wrapMetricExporter(metricExporter, kind),
+			metric.WithInterval(cfg.metricInterval),
+			metric.WithTimeout(cfg.metricTimeout),
+		)))
+	}
+	for _, reader := range cfg.metricReaders {
+		opts = append(opts, metric.WithReader(reader))
+	}
+
+	meterProvider := metric.NewMeterProvider(opts...)
 	return meterProvider, nil
 }
+
+func newLoggerProvider(ctx context.Context, cfg *config, res *resource.Resource, self *selfMetrics) (*log.LoggerProvider, error) {
+	kind := cfg.logExporter
+	if kind == "" {
+		var err error
+		if kind, err = exporterFromEnv(envLogsExporter, envOTLPLogsProtocol); err != nil {
+			return nil, err
+		}
+	}
+
+	logExporter, err := newLogExporter(ctx, kind, cfg.fileExporter)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []log.LoggerProviderOption{
+		log.WithResource(res),
+	}
+	if cfg.baggage != nil {
+		// Registered first so the exporting processor sees the attributes.
+		opts = append(opts, log.WithProcessor(NewBaggageLogProcessor(*cfg.baggage)))
+	}
+	if len(cfg.redactionRules) > 0 {
+		// Registered before every processor exporting or recording records.
+		opts = append(opts, log.WithProcessor(NewRedactingLogProcessor(cfg.redactionRules...)))
+	}
+	if logExporter != nil {
+		opts = append(opts, log.WithProcessor(log.NewBatchProcessor(self.wrapLogExporter(logExporter, kind))))
+	}
+	for _, processor := range cfg.logProcessors {
+		opts = append(opts, log.WithProcessor(processor))
+	}
+
+	loggerProvider := log.NewLoggerProvider(opts...)
+	return loggerProvider, nil
+}
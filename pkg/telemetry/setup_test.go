@@ -0,0 +1,299 @@
+package telemetry
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSetupOTelSDKExportsToCollector(t *testing.T) {
+	for _, protocol := range []string{"http/protobuf", "grpc"} {
+		t.Run(protocol, func(t *testing.T) {
+			collector := &fakeCollector{}
+			endpoint := collector.start(t, protocol)
+
+			t.Setenv("OTEL_TRACES_EXPORTER", "otlp")
+			t.Setenv("OTEL_METRICS_EXPORTER", "otlp")
+			t.Setenv("OTEL_LOGS_EXPORTER", "otlp")
+			t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", protocol)
+			t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", endpoint)
+			t.Setenv("OTEL_TRACES_SAMPLER", "always_on")
+			t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "deployment.environment.name=test")
+
+			ctx := context.Background()
+			shutdown, err := SetupOTelSDK(ctx, "setup-test", WithServiceVersion("1.2.3"))
+			if err != nil {
+				t.Fatalf("SetupOTelSDK: %v", err)
+			}
+
+			_, span := otel.Tracer("setup-test").Start(ctx, "test-span")
+			span.End()
+
+			counter, err := otel.Meter("setup-test").Int64Counter("test.counter")
+			if err != nil {
+				t.Fatalf("create counter: %v", err)
+			}
+			counter.Add(ctx, 1)
+
+			var record otellog.Record
+			record.SetBody(otellog.StringValue("test-log"))
+			global.GetLoggerProvider().Logger("setup-test").Emit(ctx, record)
+
+			// Shutdown flushes all signals.
+			if err := shutdown(ctx); err != nil {
+				t.Fatalf("shutdown: %v", err)
+			}
+
+			for _, signal := range []string{"traces", "metrics", "logs"} {
+				collector.assertResource(t, signal, map[string]string{
+					"service.name":                "setup-test",
+					"service.version":             "1.2.3",
+					"deployment.environment.name": "test",
+				})
+			}
+			collector.assertReceived(t, "traces", "test-span")
+			collector.assertReceived(t, "metrics", "test.counter")
+			collector.assertReceived(t, "logs", "test-log")
+		})
+	}
+}
+
+func TestStateConcurrentSetup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for ctx.Err() == nil {
+			_ = CurrentStatus()
+			_ = PrometheusHandler()
+			_ = LogFields(context.Background())
+			_ = UpdateRuntimeConfig("test", RuntimeConfig{})
+		}
+	}()
+
+	for range 5 {
+		shutdown, err := SetupOTelSDK(context.Background(), "state-test",
+			WithTraceExporter(ExporterNone),
+			WithMetricExporter(ExporterNone),
+			WithLogExporter(ExporterNone),
+			WithPrometheus(),
+			WithLoggerBaggageKeys("tenant"),
+		)
+		if err != nil {
+			t.Fatalf("SetupOTelSDK: %v", err)
+		}
+		if PrometheusHandler() == nil || CurrentStatus().Runtime == nil {
+			t.Errorf("state not installed by setup")
+		}
+		if err := shutdown(context.Background()); err != nil {
+			t.Errorf("shutdown: %v", err)
+		}
+	}
+	cancel()
+	wg.Wait()
+
+	if PrometheusHandler() != nil || CurrentStatus().Runtime != nil {
+		t.Errorf("state left after shutdown")
+	}
+}
+
+// fakeCollector receives OTLP exports over HTTP or gRPC and records the
+// resources and the span names, metric names and log bodies of each signal.
+type fakeCollector struct {
+	mu        sync.Mutex
+	resources map[string][]*resourcepb.Resource
+	names     map[string][]string
+}
+
+// start serves the collector until the test ends and returns its endpoint.
+func (c *fakeCollector) start(t *testing.T, protocol string) string {
+	t.Helper()
+
+	if protocol == "grpc" {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		server := grpc.NewServer()
+		coltracepb.RegisterTraceServiceServer(server, traceService{c: c})
+		colmetricpb.RegisterMetricsServiceServer(server, metricsService{c: c})
+		collogspb.RegisterLogsServiceServer(server, logsService{c: c})
+		go func() { _ = server.Serve(ln) }()
+		t.Cleanup(server.Stop)
+		return "http://" + ln.Addr().String()
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/traces", handleOTLP(t, &coltracepb.ExportTraceServiceRequest{}, func(req proto.Message) proto.Message {
+		resp, _ := c.exportTraces(req.(*coltracepb.ExportTraceServiceRequest))
+		return resp
+	}))
+	mux.HandleFunc("/v1/metrics", handleOTLP(t, &colmetricpb.ExportMetricsServiceRequest{}, func(req proto.Message) proto.Message {
+		resp, _ := c.exportMetrics(req.(*colmetricpb.ExportMetricsServiceRequest))
+		return resp
+	}))
+	mux.HandleFunc("/v1/logs", handleOTLP(t, &collogspb.ExportLogsServiceRequest{}, func(req proto.Message) proto.Message {
+		resp, _ := c.exportLogs(req.(*collogspb.ExportLogsServiceRequest))
+		return resp
+	}))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// handleOTLP decodes a binary protobuf export request into a clone of req.
+func handleOTLP(t *testing.T, req proto.Message, export func(proto.Message) proto.Message) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("read %s: %v", r.URL.Path, err)
+			return
+		}
+		msg := proto.Clone(req)
+		proto.Reset(msg)
+		if err := proto.Unmarshal(body, msg); err != nil {
+			t.Errorf("decode %s: %v", r.URL.Path, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp, err := proto.Marshal(export(msg))
+		if err != nil {
+			t.Errorf("encode %s response: %v", r.URL.Path, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		_, _ = w.Write(resp)
+	}
+}
+
+func (c *fakeCollector) record(signal string, res *resourcepb.Resource, names ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.resources == nil {
+		c.resources = make(map[string][]*resourcepb.Resource)
+		c.names = make(map[string][]string)
+	}
+	c.resources[signal] = append(c.resources[signal], res)
+	c.names[signal] = append(c.names[signal], names...)
+}
+
+func (c *fakeCollector) exportTraces(req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	for _, rs := range req.GetResourceSpans() {
+		var names []string
+		for _, ss := range rs.GetScopeSpans() {
+			for _, span := range ss.GetSpans() {
+				names = append(names, span.GetName())
+			}
+		}
+		c.record("traces", rs.GetResource(), names...)
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func (c *fakeCollector) exportMetrics(req *colmetricpb.ExportMetricsServiceRequest) (*colmetricpb.ExportMetricsServiceResponse, error) {
+	for _, rm := range req.GetResourceMetrics() {
+		var names []string
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				names = append(names, m.GetName())
+			}
+		}
+		c.record("metrics", rm.GetResource(), names...)
+	}
+	return &colmetricpb.ExportMetricsServiceResponse{}, nil
+}
+
+func (c *fakeCollector) exportLogs(req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	for _, rl := range req.GetResourceLogs() {
+		var names []string
+		for _, sl := range rl.GetScopeLogs() {
+			for _, record := range sl.GetLogRecords() {
+				names = append(names, record.GetBody().GetStringValue())
+			}
+		}
+		c.record("logs", rl.GetResource(), names...)
+	}
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+// traceService, metricsService and logsService serve the collector over
+// gRPC, where the methods of the three services are all named Export.
+type traceService struct {
+	coltracepb.UnimplementedTraceServiceServer
+	c *fakeCollector
+}
+
+func (s traceService) Export(_ context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	return s.c.exportTraces(req)
+}
+
+type metricsService struct {
+	colmetricpb.UnimplementedMetricsServiceServer
+	c *fakeCollector
+}
+
+func (s metricsService) Export(_ context.Context, req *colmetricpb.ExportMetricsServiceRequest) (*colmetricpb.ExportMetricsServiceResponse, error) {
+	return s.c.exportMetrics(req)
+}
+
+type logsService struct {
+	collogspb.UnimplementedLogsServiceServer
+	c *fakeCollector
+}
+
+func (s logsService) Export(_ context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	return s.c.exportLogs(req)
+}
+
+// assertResource checks that every export of signal carried want.
+func (c *fakeCollector) assertResource(t *testing.T, signal string, want map[string]string) {
+	t.Helper()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.resources[signal]) == 0 {
+		t.Errorf("no %s received", signal)
+		return
+	}
+	for _, res := range c.resources[signal] {
+		got := make(map[string]string)
+		for _, kv := range res.GetAttributes() {
+			got[kv.GetKey()] = kv.GetValue().GetStringValue()
+		}
+		for key, value := range want {
+			if got[key] != value {
+				t.Errorf("%s resource %s = %q, want %q", signal, key, got[key], value)
+			}
+		}
+	}
+}
+
+// assertReceived checks that name was among the spans, metrics or log
+// bodies received for signal.
+func (c *fakeCollector) assertReceived(t *testing.T, signal, name string) {
+	t.Helper()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, got := range c.names[signal] {
+		if got == name {
+			return
+		}
+	}
+	t.Errorf("%s %q not received, got %v", signal, name, c.names[signal])
+}
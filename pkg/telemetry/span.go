@@ -0,0 +1,96 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StartSpan starting a new span from or with context. Options set the span
+// kind, initial attributes and links, e.g.
+//
+//	telemetry.StartSpan(ctx, "handler:Send",
+//		oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+//		oteltrace.WithAttributes(attribute.String("notification.channel", "push")),
+//	)
+func StartSpan(ctx context.Context, spanName string, opts ...oteltrace.SpanStartOption) (context.Context, oteltrace.Span) {
+	return tracer.Start(ctx, spanName, opts...)
+}
+
+// Do runs fn inside a new span. A returned error is recorded and sets the
+// span status, a panic is recorded as an exception event with its stack trace
+// before it is propagated, and the span is always ended.
+func Do(ctx context.Context, spanName string, fn func(ctx context.Context) error, opts ...oteltrace.SpanStartOption) (err error) {
+	ctx, span := StartSpan(ctx, spanName, opts...)
+	defer func() {
+		if r := recover(); r != nil {
+			span.RecordError(fmt.Errorf("panic: %v", r), oteltrace.WithStackTrace(true))
+			span.SetStatus(codes.Error, "panic")
+			span.End()
+			panic(r)
+		}
+		RecordError(span, err)
+		span.End()
+	}()
+
+	return fn(ctx)
+}
+
+// RecordError records err on span and marks the span as failed. Errors
+// carrying a gRPC status are mapped with SetGRPCStatus. A nil err is ignored.
+func RecordError(span oteltrace.Span, err error) {
+	if err == nil {
+		return
+	}
+
+	span.RecordError(err)
+	if s, ok := status.FromError(err); ok {
+		SetGRPCStatus(span, s.Code(), s.Message())
+		return
+	}
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// SetGRPCStatus sets the span status for a gRPC status code following the
+// semantic conventions: server spans only fail on codes signalling a server
+// fault, other spans fail on every code but OK.
+func SetGRPCStatus(span oteltrace.Span, code grpccodes.Code, message string) {
+	if code == grpccodes.OK {
+		return
+	}
+	if spanKind(span) == oteltrace.SpanKindServer {
+		switch code {
+		case grpccodes.Unknown, grpccodes.DeadlineExceeded, grpccodes.Unimplemented,
+			grpccodes.Internal, grpccodes.Unavailable, grpccodes.DataLoss:
+		default:
+			return
+		}
+	}
+	span.SetStatus(codes.Error, code.String()+": "+message)
+}
+
+// SetHTTPStatus sets the span status for an HTTP response status following
+// the semantic conventions: server spans fail on 5xx, other spans on 4xx
+// and 5xx.
+func SetHTTPStatus(span oteltrace.Span, code int) {
+	failed := code >= http.StatusBadRequest
+	if spanKind(span) == oteltrace.SpanKindServer {
+		failed = code >= http.StatusInternalServerError
+	}
+	if failed || code < 100 {
+		span.SetStatus(codes.Error, http.StatusText(code))
+	}
+}
+
+// spanKind returns the kind of SDK spans and SpanKindInternal otherwise.
+func spanKind(span oteltrace.Span) oteltrace.SpanKind {
+	if s, ok := span.(interface{ SpanKind() oteltrace.SpanKind }); ok {
+		return s.SpanKind()
+	}
+	return oteltrace.SpanKindInternal
+}
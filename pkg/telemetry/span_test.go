@@ -0,0 +1,206 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDo(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	setupRuntime(t, WithSampler(sdktrace.AlwaysSample()), WithSpanProcessor(spans))
+	failure := errors.New("send failed")
+
+	t.Run("ok", func(t *testing.T) {
+		var inSpan bool
+		err := Do(context.Background(), "ok", func(ctx context.Context) error {
+			inSpan = oteltrace.SpanFromContext(ctx).SpanContext().IsValid()
+			return nil
+		}, oteltrace.WithSpanKind(oteltrace.SpanKindClient))
+		if err != nil || !inSpan {
+			t.Errorf("Do = %v, in span %t, want nil in a span", err, inSpan)
+		}
+		span := lastSpan(t, spans, "ok")
+		if span.Status().Code != codes.Unset || span.SpanKind() != oteltrace.SpanKindClient {
+			t.Errorf("span = %v %v, want an unset status and the client kind", span.Status(), span.SpanKind())
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		err := Do(context.Background(), "error", func(context.Context) error { return failure })
+		if !errors.Is(err, failure) {
+			t.Errorf("Do = %v, want %v", err, failure)
+		}
+		span := lastSpan(t, spans, "error")
+		if span.Status() != (sdktrace.Status{Code: codes.Error, Description: failure.Error()}) {
+			t.Errorf("status = %v, want the error", span.Status())
+		}
+		if event := exceptionEvent(span); event == nil {
+			t.Errorf("no exception event recorded")
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recovered %v, want the panic re-raised", r)
+			}
+			span := lastSpan(t, spans, "panic")
+			if span.Status() != (sdktrace.Status{Code: codes.Error, Description: "panic"}) {
+				t.Errorf("status = %v, want the panic", span.Status())
+			}
+			event := exceptionEvent(span)
+			if event == nil {
+				t.Fatalf("no exception event recorded")
+			}
+			var message, stack string
+			for _, attr := range event.Attributes {
+				switch attr.Key {
+				case semconv.ExceptionMessageKey:
+					message = attr.Value.AsString()
+				case semconv.ExceptionStacktraceKey:
+					stack = attr.Value.AsString()
+				}
+			}
+			if message != "panic: boom" || !strings.Contains(stack, "TestDo") {
+				t.Errorf("exception = %q with stack %q, want the panic and its stack trace", message, stack)
+			}
+		}()
+
+		_ = Do(context.Background(), "panic", func(context.Context) error { panic("boom") })
+	})
+}
+
+func TestRecordError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want sdktrace.Status
+	}{
+		{name: "nil", want: sdktrace.Status{Code: codes.Unset}},
+		{name: "error", err: errors.New("failed"), want: sdktrace.Status{Code: codes.Error, Description: "failed"}},
+		{
+			name: "gRPC status",
+			err:  status.Error(grpccodes.Unavailable, "no connection"),
+			want: sdktrace.Status{Code: codes.Error, Description: "Unavailable: no connection"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := recordSpan(t, oteltrace.SpanKindClient, func(span oteltrace.Span) { RecordError(span, tt.err) })
+			if span.Status() != tt.want {
+				t.Errorf("status = %v, want %v", span.Status(), tt.want)
+			}
+			if recorded := exceptionEvent(span) != nil; recorded != (tt.err != nil) {
+				t.Errorf("exception recorded = %t, want %t", recorded, tt.err != nil)
+			}
+		})
+	}
+}
+
+func TestSetGRPCStatus(t *testing.T) {
+	tests := []struct {
+		code       grpccodes.Code
+		kind       oteltrace.SpanKind
+		wantFailed bool
+	}{
+		{code: grpccodes.OK, kind: oteltrace.SpanKindClient},
+		{code: grpccodes.OK, kind: oteltrace.SpanKindServer},
+		{code: grpccodes.NotFound, kind: oteltrace.SpanKindClient, wantFailed: true},
+		{code: grpccodes.NotFound, kind: oteltrace.SpanKindServer},
+		{code: grpccodes.InvalidArgument, kind: oteltrace.SpanKindServer},
+		{code: grpccodes.Internal, kind: oteltrace.SpanKindServer, wantFailed: true},
+		{code: grpccodes.Unavailable, kind: oteltrace.SpanKindServer, wantFailed: true},
+		{code: grpccodes.DeadlineExceeded, kind: oteltrace.SpanKindInternal, wantFailed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind.String()+"/"+tt.code.String(), func(t *testing.T) {
+			span := recordSpan(t, tt.kind, func(span oteltrace.Span) { SetGRPCStatus(span, tt.code, "message") })
+			want := sdktrace.Status{Code: codes.Unset}
+			if tt.wantFailed {
+				want = sdktrace.Status{Code: codes.Error, Description: tt.code.String() + ": message"}
+			}
+			if span.Status() != want {
+				t.Errorf("status = %v, want %v", span.Status(), want)
+			}
+		})
+	}
+}
+
+func TestSetHTTPStatus(t *testing.T) {
+	tests := []struct {
+		code       int
+		kind       oteltrace.SpanKind
+		wantFailed bool
+	}{
+		{code: http.StatusOK, kind: oteltrace.SpanKindServer},
+		{code: http.StatusOK, kind: oteltrace.SpanKindClient},
+		{code: http.StatusNotFound, kind: oteltrace.SpanKindServer},
+		{code: http.StatusNotFound, kind: oteltrace.SpanKindClient, wantFailed: true},
+		{code: http.StatusBadRequest, kind: oteltrace.SpanKindInternal, wantFailed: true},
+		{code: http.StatusServiceUnavailable, kind: oteltrace.SpanKindServer, wantFailed: true},
+		{code: http.StatusInternalServerError, kind: oteltrace.SpanKindClient, wantFailed: true},
+		{code: 42, kind: oteltrace.SpanKindServer, wantFailed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind.String()+"/"+http.StatusText(tt.code), func(t *testing.T) {
+			span := recordSpan(t, tt.kind, func(span oteltrace.Span) { SetHTTPStatus(span, tt.code) })
+			want := sdktrace.Status{Code: codes.Unset}
+			if tt.wantFailed {
+				want = sdktrace.Status{Code: codes.Error, Description: http.StatusText(tt.code)}
+			}
+			if span.Status() != want {
+				t.Errorf("status = %v, want %v", span.Status(), want)
+			}
+		})
+	}
+}
+
+// recordSpan returns the span of kind ended after fn was applied to it.
+func recordSpan(t *testing.T, kind oteltrace.SpanKind, fn func(oteltrace.Span)) sdktrace.ReadOnlySpan {
+	t.Helper()
+
+	spans := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	_, span := provider.Tracer("span-test").Start(context.Background(), "recorded", oteltrace.WithSpanKind(kind))
+	fn(span)
+	span.End()
+	return spans.Ended()[0]
+}
+
+// lastSpan returns the last ended span, failing the test unless it is
+// called name.
+func lastSpan(t *testing.T, spans *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+
+	ended := spans.Ended()
+	if len(ended) == 0 || ended[len(ended)-1].Name() != name {
+		t.Fatalf("spans = %v, want %s last", ended, name)
+	}
+	return ended[len(ended)-1]
+}
+
+// exceptionEvent returns the exception event of span, or nil.
+func exceptionEvent(span sdktrace.ReadOnlySpan) *sdktrace.Event {
+	for _, event := range span.Events() {
+		if event.Name == semconv.ExceptionEventName {
+			return &event
+		}
+	}
+	return nil
+}
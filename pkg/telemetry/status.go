@@ -0,0 +1,129 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Status describes the telemetry pipeline set up by SetupOTelSDK.
+type Status struct {
+	// Healthy is false while any exporter is failing.
+	Healthy   bool             `json:"healthy"`
+	Exporters []ExporterStatus `json:"exporters"`
+	// SpanQueue is nil when no trace exporter is configured.
+	SpanQueue *SpanQueueStatus `json:"span_queue,omitempty"`
+	Errors    ErrorStatus      `json:"errors"`
+	// Runtime is nil before SetupOTelSDK.
+	Runtime *RuntimeStatus `json:"runtime,omitempty"`
+}
+
+// ExporterStatus reports the exports of one signal.
+type ExporterStatus struct {
+	Signal   string   `json:"signal"`
+	Exporter Exporter `json:"exporter"`
+	// Healthy is false when the last export failed.
+	Healthy             bool       `json:"healthy"`
+	Exports             uint64     `json:"exports"`
+	Failures            uint64     `json:"failures"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	LastFailure         *time.Time `json:"last_failure,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	// FailingSince is the time of the first of the consecutive failures.
+	FailingSince *time.Time `json:"failing_since,omitempty"`
+}
+
+// SpanQueueStatus reports the spans waiting for the trace exporter.
+type SpanQueueStatus struct {
+	Size     int64 `json:"size"`
+	Capacity int64 `json:"capacity"`
+	Dropped  int64 `json:"dropped"`
+}
+
+// ErrorStatus counts the errors reported to otel.Handle.
+type ErrorStatus struct {
+	Logged     uint64 `json:"logged"`
+	Suppressed uint64 `json:"suppressed"`
+}
+
+// CurrentStatus returns the status of the pipeline set up by SetupOTelSDK,
+// or a healthy zero Status before it is set up.
+func CurrentStatus() Status {
+	status := Status{Healthy: true, Exporters: []ExporterStatus{}}
+	state := loadState()
+	if control := state.runtime; control != nil {
+		runtime := control.status()
+		status.Runtime = &runtime
+	}
+	m := state.self
+	if m == nil {
+		return status
+	}
+
+	for _, health := range m.exporters {
+		exporter := health.status()
+		status.Healthy = status.Healthy && exporter.Healthy
+		status.Exporters = append(status.Exporters, exporter)
+	}
+	if m.queue != nil {
+		status.SpanQueue = &SpanQueueStatus{
+			Size:     m.queue.depth(),
+			Capacity: m.queue.capacity,
+			Dropped:  m.queue.dropped.Load(),
+		}
+	}
+	status.Errors.Logged, status.Errors.Suppressed = m.errors.counts()
+	return status
+}
+
+// StatusHandler returns the handler serving CurrentStatus as JSON, e.g. on
+// /debug/telemetry. It answers 503 Service Unavailable while an exporter is
+// failing. The export errors reveal the collector endpoints, so it must only
+// be served to operators, e.g. on a loopback address.
+func StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		status := CurrentStatus()
+
+		w.Header().Set("Content-Type", "application/json")
+		if !status.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(status)
+	})
+}
+
+// CheckExporters returns a check failing once an exporter has been failing
+// for longer than failingFor. A collector outage alone leaves a process able
+// to serve, so it suits an opt-in readiness check, e.g. health.Health.AddCheck,
+// for processes that must not lose their telemetry.
+func CheckExporters(failingFor time.Duration) func(context.Context) error {
+	return func(context.Context) error {
+		for _, exporter := range CurrentStatus().Exporters {
+			if exporter.FailingSince != nil && time.Since(*exporter.FailingSince) > failingFor {
+				return fmt.Errorf("telemetry: %s exporter %s is failing since %s: %s",
+					exporter.Signal, exporter.Exporter, exporter.FailingSince.Format(time.RFC3339), exporter.LastError)
+			}
+		}
+		return nil
+	}
+}
+
+// SpanQueueCheck returns a check failing while the span queue holds more
+// than the fraction limit of its capacity, before spans are dropped.
+func SpanQueueCheck(limit float64) func(context.Context) error {
+	return func(context.Context) error {
+		queue := CurrentStatus().SpanQueue
+		if queue == nil || queue.Capacity == 0 {
+			return nil
+		}
+		if float64(queue.Size) > limit*float64(queue.Capacity) {
+			return fmt.Errorf("telemetry: span queue holds %d of %d spans", queue.Size, queue.Capacity)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,100 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestStatusHandler(t *testing.T) {
+	t.Run("not set up", func(t *testing.T) {
+		status, code := serveStatus(t)
+		if code != http.StatusOK || !status.Healthy || len(status.Exporters) != 0 || status.Runtime != nil {
+			t.Errorf("status = %d %+v, want 200 and a healthy zero status", code, status)
+		}
+	})
+
+	for _, tt := range []struct {
+		name       string
+		collector  int
+		wantStatus int
+	}{
+		{name: "exporting", collector: http.StatusOK, wantStatus: http.StatusOK},
+		{name: "export failing", collector: http.StatusBadRequest, wantStatus: http.StatusServiceUnavailable},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.collector)
+			}))
+			t.Cleanup(collector.Close)
+			t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", collector.URL)
+			setupRuntime(t, WithTraceExporter(ExporterOTLPHTTP), WithSampler(trace.AlwaysSample()))
+
+			_, span := otel.Tracer("status-test").Start(context.Background(), "exported")
+			span.End()
+			_ = otel.GetTracerProvider().(*trace.TracerProvider).ForceFlush(context.Background())
+
+			status, code := serveStatus(t)
+			if code != tt.wantStatus {
+				t.Errorf("status code = %d, want %d", code, tt.wantStatus)
+			}
+			if len(status.Exporters) != 1 {
+				t.Fatalf("exporters = %+v, want the trace exporter", status.Exporters)
+			}
+			exporter := status.Exporters[0]
+			healthy := tt.collector == http.StatusOK
+			if exporter.Signal != signalTraces || exporter.Exports != 1 || exporter.Healthy != healthy || status.Healthy != healthy {
+				t.Errorf("exporter = %+v, want 1 export, healthy %t", exporter, healthy)
+			}
+			if !healthy && (exporter.Failures != 1 || exporter.ConsecutiveFailures != 1 || exporter.LastError == "") {
+				t.Errorf("exporter = %+v, want 1 failure with its error", exporter)
+			}
+			if status.SpanQueue == nil || status.SpanQueue.Size != 0 || status.Runtime == nil {
+				t.Errorf("status = %+v, want an empty span queue and the runtime settings", status)
+			}
+		})
+	}
+}
+
+func TestCheckExporters(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	t.Cleanup(collector.Close)
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", collector.URL)
+	setupRuntime(t, WithTraceExporter(ExporterOTLPHTTP), WithSampler(trace.AlwaysSample()))
+
+	if err := CheckExporters(0)(context.Background()); err != nil {
+		t.Errorf("check = %v before any export, want nil", err)
+	}
+
+	_, span := otel.Tracer("status-test").Start(context.Background(), "exported")
+	span.End()
+	_ = otel.GetTracerProvider().(*trace.TracerProvider).ForceFlush(context.Background())
+
+	if err := CheckExporters(time.Hour)(context.Background()); err != nil {
+		t.Errorf("check = %v within the limit, want nil", err)
+	}
+	if err := CheckExporters(0)(context.Background()); err == nil {
+		t.Errorf("check = nil past the limit, want the export error")
+	}
+}
+
+func serveStatus(t *testing.T) (Status, int) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	StatusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/telemetry", nil))
+
+	var status Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body, err)
+	}
+	return status, rec.Code
+}
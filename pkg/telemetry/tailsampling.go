@@ -0,0 +1,364 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultTailDecisionWait     = 10 * time.Second
+	defaultTailMaxTraces        = 10000
+	defaultTailMaxSpansPerTrace = 1000
+
+	instrumentationName = "github.com/wahyurudiyan/go-otel-context-propagation/pkg/telemetry"
+)
+
+// Reasons reported on the tail sampling decision metric.
+const (
+	tailReasonError     = "error"
+	tailReasonLatency   = "latency"
+	tailReasonAttribute = "attribute"
+	tailReasonRatio     = "ratio"
+)
+
+// TailSamplingConfig configures NewTailSamplingProcessor.
+type TailSamplingConfig struct {
+	// DecisionWait is how long spans of a trace are buffered, counted from
+	// the first ended span, before the trace is kept or dropped.
+	DecisionWait time.Duration
+	// MaxTraces bounds the number of buffered traces. When it is reached the
+	// oldest trace is decided early.
+	MaxTraces int
+	// MaxSpansPerTrace bounds the spans buffered per trace. Extra spans are
+	// dropped but still take part in the decision.
+	MaxSpansPerTrace int
+	// LatencyThreshold keeps traces with a span lasting at least this long.
+	// Zero disables the check.
+	LatencyThreshold time.Duration
+	// Attributes keeps traces with a span carrying any of these attributes.
+	Attributes []attribute.KeyValue
+	// SampleRatio is the share of the remaining traces that are kept.
+	SampleRatio float64
+	// MeterProvider records the decision metrics. The global provider is
+	// used when it is nil.
+	MeterProvider otelmetric.MeterProvider
+}
+
+type tailTrace struct {
+	spans   []trace.ReadOnlySpan
+	dropped int
+	reason  string
+	expires time.Time
+}
+
+type tailSamplingProcessor struct {
+	next  trace.SpanProcessor
+	cfg   TailSamplingConfig
+	ratio trace.Sampler
+
+	mu      sync.Mutex
+	traces  map[oteltrace.TraceID]*tailTrace
+	pending []oteltrace.TraceID
+
+	// decided remembers recent decisions, bounded by MaxTraces, so spans
+	// ending after their trace was decided follow the same decision.
+	decided      map[oteltrace.TraceID]bool
+	decidedOrder []oteltrace.TraceID
+
+	// instruments is set once the meter provider exists, which is after
+	// the tracer provider when the processor is set up by SetupOTelSDK.
+	instruments atomic.Pointer[tailInstruments]
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+type tailInstruments struct {
+	decisions    otelmetric.Int64Counter
+	droppedSpan  otelmetric.Int64Counter
+	registration otelmetric.Registration
+}
+
+// tailDecision is the outcome of a buffered trace, applied by forward once
+// p.mu is released.
+type tailDecision struct {
+	// spans are forwarded to the next processor, none for a dropped trace.
+	spans   []trace.ReadOnlySpan
+	reason  string
+	dropped int
+}
+
+var _ trace.SpanProcessor = (*tailSamplingProcessor)(nil)
+
+// NewTailSamplingProcessor buffers ended spans per trace and forwards the
+// spans of kept traces to next, typically a batch span processor. A trace is
+// kept when any span has an error status, exceeds LatencyThreshold or carries
+// one of Attributes; the others are kept with SampleRatio.
+//
+// Only recorded spans reach the processor, so the head sampler must sample
+// every trace that should be considered.
+func NewTailSamplingProcessor(next trace.SpanProcessor, cfg TailSamplingConfig) trace.SpanProcessor {
+	p := newTailSamplingProcessor(next, cfg)
+	provider := cfg.MeterProvider
+	if provider == nil {
+		provider = otel.GetMeterProvider()
+	}
+	if err := p.start(provider); err != nil {
+		otel.Handle(err)
+	}
+	return p
+}
+
+// newTailSamplingProcessor returns the processor without its metrics, which
+// start creates.
+func newTailSamplingProcessor(next trace.SpanProcessor, cfg TailSamplingConfig) *tailSamplingProcessor {
+	if cfg.DecisionWait <= 0 {
+		cfg.DecisionWait = defaultTailDecisionWait
+	}
+	if cfg.MaxTraces <= 0 {
+		cfg.MaxTraces = defaultTailMaxTraces
+	}
+	if cfg.MaxSpansPerTrace <= 0 {
+		cfg.MaxSpansPerTrace = defaultTailMaxSpansPerTrace
+	}
+
+	p := &tailSamplingProcessor{
+		next:    next,
+		cfg:     cfg,
+		ratio:   trace.TraceIDRatioBased(cfg.SampleRatio),
+		traces:  make(map[oteltrace.TraceID]*tailTrace),
+		decided: make(map[oteltrace.TraceID]bool),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go p.run()
+	return p
+}
+
+// start creates the instruments of p from provider.
+func (p *tailSamplingProcessor) start(provider otelmetric.MeterProvider) error {
+	meter := provider.Meter(instrumentationName)
+
+	var inst tailInstruments
+	var err error
+	inst.decisions, err = meter.Int64Counter("telemetry.tail_sampling.traces",
+		otelmetric.WithDescription("Traces decided by the tail sampling processor."),
+		otelmetric.WithUnit("{trace}"))
+	if err != nil {
+		return err
+	}
+
+	inst.droppedSpan, err = meter.Int64Counter("telemetry.tail_sampling.spans.dropped",
+		otelmetric.WithDescription("Spans dropped because their trace exceeded the span limit."),
+		otelmetric.WithUnit("{span}"))
+	if err != nil {
+		return err
+	}
+
+	buffered, err := meter.Int64ObservableGauge("telemetry.tail_sampling.traces.buffered",
+		otelmetric.WithDescription("Traces waiting for a tail sampling decision."),
+		otelmetric.WithUnit("{trace}"))
+	if err != nil {
+		return err
+	}
+
+	inst.registration, err = meter.RegisterCallback(func(_ context.Context, o otelmetric.Observer) error {
+		p.mu.Lock()
+		n := len(p.traces)
+		p.mu.Unlock()
+		o.ObserveInt64(buffered, int64(n))
+		return nil
+	}, buffered)
+	if err != nil {
+		return err
+	}
+
+	p.instruments.Store(&inst)
+	return nil
+}
+
+func (p *tailSamplingProcessor) OnStart(parent context.Context, s trace.ReadWriteSpan) {
+	p.next.OnStart(parent, s)
+}
+
+func (p *tailSamplingProcessor) OnEnd(s trace.ReadOnlySpan) {
+	traceID := s.SpanContext().TraceID()
+
+	p.mu.Lock()
+	if keep, ok := p.decided[traceID]; ok {
+		p.mu.Unlock()
+		if keep {
+			p.next.OnEnd(s)
+		}
+		return
+	}
+
+	var early *tailDecision
+	t, ok := p.traces[traceID]
+	if !ok {
+		if len(p.traces) >= p.cfg.MaxTraces {
+			d := p.decideLocked(p.pending[0])
+			early = &d
+			p.pending = p.pending[1:]
+		}
+		t = &tailTrace{expires: time.Now().Add(p.cfg.DecisionWait)}
+		p.traces[traceID] = t
+		p.pending = append(p.pending, traceID)
+	}
+
+	if t.reason == "" {
+		t.reason = p.keepReason(s)
+	}
+	if len(t.spans) < p.cfg.MaxSpansPerTrace {
+		t.spans = append(t.spans, s)
+	} else {
+		t.dropped++
+	}
+	p.mu.Unlock()
+
+	if early != nil {
+		p.forward(*early)
+	}
+}
+
+// keepReason reports why s alone makes its trace worth keeping.
+func (p *tailSamplingProcessor) keepReason(s trace.ReadOnlySpan) string {
+	if s.Status().Code == codes.Error {
+		return tailReasonError
+	}
+	if p.cfg.LatencyThreshold > 0 && s.EndTime().Sub(s.StartTime()) >= p.cfg.LatencyThreshold {
+		return tailReasonLatency
+	}
+	for _, want := range p.cfg.Attributes {
+		for _, got := range s.Attributes() {
+			if got.Key == want.Key && got.Value == want.Value {
+				return tailReasonAttribute
+			}
+		}
+	}
+	return ""
+}
+
+// decideLocked keeps or drops a buffered trace. The caller holds p.mu,
+// removes traceID from p.pending and passes the decision to forward once
+// p.mu is released.
+func (p *tailSamplingProcessor) decideLocked(traceID oteltrace.TraceID) tailDecision {
+	t := p.traces[traceID]
+	delete(p.traces, traceID)
+
+	reason := t.reason
+	if reason == "" {
+		result := p.ratio.ShouldSample(trace.SamplingParameters{TraceID: traceID})
+		if result.Decision == trace.RecordAndSample {
+			reason = tailReasonRatio
+		}
+	}
+
+	if len(p.decidedOrder) >= p.cfg.MaxTraces {
+		delete(p.decided, p.decidedOrder[0])
+		p.decidedOrder = p.decidedOrder[1:]
+	}
+	p.decided[traceID] = reason != ""
+	p.decidedOrder = append(p.decidedOrder, traceID)
+
+	d := tailDecision{reason: reason, dropped: t.dropped}
+	if reason != "" {
+		d.spans = t.spans
+	}
+	return d
+}
+
+// forward passes the spans of a kept trace to the next processor and
+// records the decision.
+func (p *tailSamplingProcessor) forward(d tailDecision) {
+	decision := "dropped"
+	if d.reason != "" {
+		decision = "kept"
+		for _, s := range d.spans {
+			p.next.OnEnd(s)
+		}
+	}
+
+	inst := p.instruments.Load()
+	if inst == nil {
+		return
+	}
+	ctx := context.Background()
+	inst.decisions.Add(ctx, 1, otelmetric.WithAttributes(
+		attribute.String("decision", decision),
+		attribute.String("reason", d.reason),
+	))
+	if d.dropped > 0 {
+		inst.droppedSpan.Add(ctx, int64(d.dropped))
+	}
+}
+
+// decideExpired decides every trace whose window ended before now, or every
+// buffered trace when all is set.
+func (p *tailSamplingProcessor) decideExpired(now time.Time, all bool) {
+	var decisions []tailDecision
+
+	p.mu.Lock()
+	for len(p.pending) > 0 {
+		traceID := p.pending[0]
+		if !all && p.traces[traceID].expires.After(now) {
+			break
+		}
+		decisions = append(decisions, p.decideLocked(traceID))
+		p.pending = p.pending[1:]
+	}
+	p.mu.Unlock()
+
+	for _, d := range decisions {
+		p.forward(d)
+	}
+}
+
+func (p *tailSamplingProcessor) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(max(p.cfg.DecisionWait/10, 100*time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			p.decideExpired(now, false)
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// ForceFlush decides every buffered trace and flushes the next processor.
+func (p *tailSamplingProcessor) ForceFlush(ctx context.Context) error {
+	p.decideExpired(time.Now(), true)
+	return p.next.ForceFlush(ctx)
+}
+
+// Shutdown decides every buffered trace and shuts the next processor down.
+func (p *tailSamplingProcessor) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+	<-p.done
+
+	p.decideExpired(time.Now(), true)
+	var err error
+	if inst := p.instruments.Load(); inst != nil {
+		err = inst.registration.Unregister()
+	}
+	return errors.Join(err, p.next.Shutdown(ctx))
+}
@@ -0,0 +1,193 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestTailSamplingProcessorExpiry(t *testing.T) {
+	p, recorder, reader := newTestTailSampling(t, TailSamplingConfig{DecisionWait: time.Hour})
+
+	begin := time.Now()
+	p.OnEnd(tailSpan(1, 1, codes.Error))
+	p.OnEnd(tailSpan(1, 2, codes.Unset))
+	p.OnEnd(tailSpan(2, 1, codes.Unset))
+
+	p.(*tailSamplingProcessor).decideExpired(begin, false)
+	if ended := recorder.Ended(); len(ended) != 0 {
+		t.Fatalf("forwarded %d spans before the decision wait, want none", len(ended))
+	}
+
+	p.(*tailSamplingProcessor).decideExpired(begin.Add(time.Hour+time.Second), false)
+	assertForwarded(t, recorder, 1, 1, 2)
+
+	// Spans ending after the decision follow it.
+	p.OnEnd(tailSpan(1, 3, codes.Unset))
+	p.OnEnd(tailSpan(2, 2, codes.Error))
+	assertForwarded(t, recorder, 1, 1, 2, 3)
+
+	assertTailDecisions(t, reader, map[[2]string]int64{
+		{"kept", tailReasonError}: 1,
+		{"dropped", ""}:           1,
+	})
+}
+
+func TestTailSamplingProcessorMaxTraces(t *testing.T) {
+	p, recorder, reader := newTestTailSampling(t, TailSamplingConfig{
+		DecisionWait: time.Hour,
+		MaxTraces:    2,
+		Attributes:   []attribute.KeyValue{attribute.Bool("debug", true)},
+	})
+
+	kept := tracetest.SpanStub{
+		SpanContext: tailSpanContext(1, 1),
+		Attributes:  []attribute.KeyValue{attribute.Bool("debug", true)},
+	}.Snapshot()
+	p.OnEnd(kept)
+	p.OnEnd(tailSpan(2, 1, codes.Unset))
+	if ended := recorder.Ended(); len(ended) != 0 {
+		t.Fatalf("forwarded %d spans below the trace limit, want none", len(ended))
+	}
+
+	// The oldest trace is decided early to make room for the third.
+	p.OnEnd(tailSpan(3, 1, codes.Unset))
+	assertForwarded(t, recorder, 1, 1)
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	buffered, ok := findMetric(rm, "telemetry.tail_sampling.traces.buffered").Data.(metricdata.Gauge[int64])
+	if !ok || len(buffered.DataPoints) != 1 || buffered.DataPoints[0].Value != 2 {
+		t.Errorf("buffered traces = %+v, want 2", buffered.DataPoints)
+	}
+	assertTailDecisions(t, reader, map[[2]string]int64{{"kept", tailReasonAttribute}: 1})
+}
+
+func TestTailSamplingProcessorShutdown(t *testing.T) {
+	p, recorder, reader := newTestTailSampling(t, TailSamplingConfig{
+		DecisionWait:     time.Hour,
+		MaxSpansPerTrace: 2,
+	})
+
+	p.OnEnd(tailSpan(1, 1, codes.Error))
+	p.OnEnd(tailSpan(1, 2, codes.Unset))
+	p.OnEnd(tailSpan(1, 3, codes.Unset))
+	p.OnEnd(tailSpan(2, 1, codes.Unset))
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	// Spans over MaxSpansPerTrace are dropped.
+	assertForwarded(t, recorder, 1, 1, 2)
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	dropped, ok := findMetric(rm, "telemetry.tail_sampling.spans.dropped").Data.(metricdata.Sum[int64])
+	if !ok || len(dropped.DataPoints) != 1 || dropped.DataPoints[0].Value != 1 {
+		t.Errorf("dropped spans = %+v, want 1", dropped.DataPoints)
+	}
+	if m := findMetric(rm, "telemetry.tail_sampling.traces.buffered"); m.Name != "" {
+		t.Errorf("buffered traces still observed after shutdown")
+	}
+	assertTailDecisions(t, reader, map[[2]string]int64{
+		{"kept", tailReasonError}: 1,
+		{"dropped", ""}:           1,
+	})
+}
+
+// newTestTailSampling returns a processor keeping no trace by ratio,
+// forwarding to the returned recorder and recording its metrics with the
+// returned reader.
+func newTestTailSampling(t *testing.T, cfg TailSamplingConfig) (sdktrace.SpanProcessor, *tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	t.Helper()
+
+	reader := sdkmetric.NewManualReader()
+	cfg.MeterProvider = sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	recorder := tracetest.NewSpanRecorder()
+	p := NewTailSamplingProcessor(recorder, cfg)
+	t.Cleanup(func() { _ = p.Shutdown(context.Background()) })
+	return p, recorder, reader
+}
+
+func tailSpanContext(traceID, spanID byte) oteltrace.SpanContext {
+	return oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    oteltrace.TraceID{traceID},
+		SpanID:     oteltrace.SpanID{spanID},
+		TraceFlags: oteltrace.FlagsSampled,
+	})
+}
+
+func tailSpan(traceID, spanID byte, code codes.Code) sdktrace.ReadOnlySpan {
+	return tracetest.SpanStub{
+		SpanContext: tailSpanContext(traceID, spanID),
+		Status:      sdktrace.Status{Code: code},
+	}.Snapshot()
+}
+
+// assertForwarded checks that the spans of trace traceID with spanIDs, and
+// no other spans, were forwarded.
+func assertForwarded(t *testing.T, recorder *tracetest.SpanRecorder, traceID byte, spanIDs ...byte) {
+	t.Helper()
+
+	ended := recorder.Ended()
+	if len(ended) != len(spanIDs) {
+		t.Fatalf("forwarded %d spans, want %d", len(ended), len(spanIDs))
+	}
+	for i, s := range ended {
+		if want := tailSpanContext(traceID, spanIDs[i]); !s.SpanContext().Equal(want) {
+			t.Errorf("span %d = %s/%s, want %s/%s", i,
+				s.SpanContext().TraceID(), s.SpanContext().SpanID(), want.TraceID(), want.SpanID())
+		}
+	}
+}
+
+// assertTailDecisions checks the traces counted by decision and reason.
+func assertTailDecisions(t *testing.T, reader *sdkmetric.ManualReader, want map[[2]string]int64) {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	sum, _ := findMetric(rm, "telemetry.tail_sampling.traces").Data.(metricdata.Sum[int64])
+
+	got := make(map[[2]string]int64)
+	for _, point := range sum.DataPoints {
+		decision, _ := point.Attributes.Value("decision")
+		reason, _ := point.Attributes.Value("reason")
+		got[[2]string{decision.AsString(), reason.AsString()}] += point.Value
+	}
+	if len(got) != len(want) {
+		t.Errorf("decisions = %v, want %v", got, want)
+		return
+	}
+	for key, n := range want {
+		if got[key] != n {
+			t.Errorf("decisions = %v, want %v", got, want)
+			return
+		}
+	}
+}
+
+func findMetric(rm metricdata.ResourceMetrics, name string) metricdata.Metrics {
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m
+			}
+		}
+	}
+	return metricdata.Metrics{}
+}
@@ -0,0 +1,137 @@
+package telemetrytest
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AssertSameTrace checks that all spans belong to one trace.
+func AssertSameTrace(t testing.TB, spans ...sdktrace.ReadOnlySpan) {
+	t.Helper()
+
+	if len(spans) == 0 {
+		t.Errorf("telemetrytest: no spans to compare")
+		return
+	}
+	want := spans[0].SpanContext().TraceID()
+	for _, span := range spans[1:] {
+		if got := span.SpanContext().TraceID(); got != want {
+			t.Errorf("telemetrytest: span %q has trace ID %s, want %s (from %q)", span.Name(), got, want, spans[0].Name())
+		}
+	}
+}
+
+// AssertChildOf checks that parent is the direct parent of child.
+func AssertChildOf(t testing.TB, parent, child sdktrace.ReadOnlySpan) {
+	t.Helper()
+
+	if got, want := child.Parent().SpanID(), parent.SpanContext().SpanID(); got != want {
+		t.Errorf("telemetrytest: span %q has parent %s, want %s (%q)", child.Name(), got, want, parent.Name())
+	}
+}
+
+// AssertChain checks that spans contain a span for every name, each one a
+// descendant of the previous, e.g. a client controller span, the handler
+// span below it and the server span handling the resulting request.
+// Intermediate spans, such as the ones of HTTP or gRPC instrumentation,
+// may sit between two links of the chain.
+func AssertChain(t testing.TB, spans []sdktrace.ReadOnlySpan, names ...string) {
+	t.Helper()
+
+	if len(names) == 0 {
+		return
+	}
+
+	byID := make(map[trace.SpanID]sdktrace.ReadOnlySpan, len(spans))
+	for _, span := range spans {
+		byID[span.SpanContext().SpanID()] = span
+	}
+
+	var found func(ancestor sdktrace.ReadOnlySpan, rest []string) bool
+	found = func(ancestor sdktrace.ReadOnlySpan, rest []string) bool {
+		if len(rest) == 0 {
+			return true
+		}
+		for _, span := range spans {
+			if span.Name() == rest[0] && descendsFrom(byID, span, ancestor) && found(span, rest[1:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, span := range spans {
+		if span.Name() == names[0] && found(span, names[1:]) {
+			return
+		}
+	}
+	t.Errorf("telemetrytest: no span chain %v in %v", names, spanNames(spans))
+}
+
+// descendsFrom reports whether ancestor is found walking up the parents of
+// span.
+func descendsFrom(byID map[trace.SpanID]sdktrace.ReadOnlySpan, span, ancestor sdktrace.ReadOnlySpan) bool {
+	want := ancestor.SpanContext().SpanID()
+	for parent := span.Parent().SpanID(); parent.IsValid(); {
+		if parent == want {
+			return true
+		}
+		next, ok := byID[parent]
+		if !ok {
+			return false
+		}
+		parent = next.Parent().SpanID()
+	}
+	return false
+}
+
+// AssertAttributes checks that span has every attribute in attrs with the
+// same value. Other attributes are ignored.
+func AssertAttributes(t testing.TB, span sdktrace.ReadOnlySpan, attrs ...attribute.KeyValue) {
+	t.Helper()
+
+	got := attribute.NewSet(span.Attributes()...)
+	for _, want := range attrs {
+		value, ok := got.Value(want.Key)
+		if !ok {
+			t.Errorf("telemetrytest: span %q has no attribute %q", span.Name(), want.Key)
+			continue
+		}
+		if value != want.Value {
+			t.Errorf("telemetrytest: span %q attribute %q = %s, want %s", span.Name(), want.Key, value.Emit(), want.Value.Emit())
+		}
+	}
+}
+
+// AssertStatus checks the status code of span.
+func AssertStatus(t testing.TB, span sdktrace.ReadOnlySpan, code codes.Code) {
+	t.Helper()
+
+	if got := span.Status().Code; got != code {
+		t.Errorf("telemetrytest: span %q has status %s (%q), want %s", span.Name(), got, span.Status().Description, code)
+	}
+}
+
+// AssertLogInSpan checks that record was emitted within span.
+func AssertLogInSpan(t testing.TB, record log.Record, span sdktrace.ReadOnlySpan) {
+	t.Helper()
+
+	if record.TraceID() != span.SpanContext().TraceID() || record.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("telemetrytest: log %q has trace %s span %s, want %s %s (%q)",
+			record.Body().String(), record.TraceID(), record.SpanID(),
+			span.SpanContext().TraceID(), span.SpanContext().SpanID(), span.Name())
+	}
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+	return names
+}
@@ -0,0 +1,150 @@
+// Package telemetrytest records spans, metrics and logs in memory so tests
+// can assert on the telemetry produced by instrumented code.
+package telemetrytest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/wahyurudiyan/go-otel-context-propagation/pkg/telemetry"
+	"go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+// Recorder holds the telemetry emitted while it is installed.
+type Recorder struct {
+	spans  *tracetest.SpanRecorder
+	reader *metric.ManualReader
+	logs   *logRecorder
+}
+
+// Install sets up the SDK like telemetry.SetupOTelSDK, with every exporter
+// disabled and the returned Recorder collecting all spans, metrics and log
+// records instead. The global zap logger is replaced by one bridged to the
+// recorder, so telemetry.Logger records are captured too. Everything is
+// restored when the test ends.
+//
+// opts are applied after the recorder's own options and may override them,
+// e.g. to set a sampler or baggage promotion.
+func Install(t testing.TB, serviceName string, opts ...telemetry.Option) *Recorder {
+	t.Helper()
+
+	r := &Recorder{
+		spans:  tracetest.NewSpanRecorder(),
+		reader: metric.NewManualReader(),
+		logs:   &logRecorder{},
+	}
+
+	opts = append([]telemetry.Option{
+		telemetry.WithTraceExporter(telemetry.ExporterNone),
+		telemetry.WithMetricExporter(telemetry.ExporterNone),
+		telemetry.WithLogExporter(telemetry.ExporterNone),
+		telemetry.WithSampler(sdktrace.ParentBased(sdktrace.AlwaysSample())),
+		telemetry.WithSpanProcessor(r.spans),
+		telemetry.WithMetricReader(r.reader),
+		telemetry.WithLogProcessor(r.logs),
+	}, opts...)
+
+	shutdown, err := telemetry.SetupOTelSDK(context.Background(), serviceName, opts...)
+	if err != nil {
+		t.Fatalf("telemetrytest: set up SDK: %v", err)
+	}
+
+	restoreLogger := zap.ReplaceGlobals(zap.NewNop().WithOptions(telemetry.ZapOption(serviceName)))
+	t.Cleanup(func() {
+		restoreLogger()
+		if err := shutdown(context.Background()); err != nil {
+			t.Errorf("telemetrytest: shut down SDK: %v", err)
+		}
+	})
+	return r
+}
+
+// Spans returns the ended spans in the order they ended.
+func (r *Recorder) Spans() []sdktrace.ReadOnlySpan {
+	return r.spans.Ended()
+}
+
+// SpansByName returns the ended spans called name.
+func (r *Recorder) SpansByName(name string) []sdktrace.ReadOnlySpan {
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range r.spans.Ended() {
+		if span.Name() == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// Span returns the first ended span called name, failing the test when
+// there is none.
+func (r *Recorder) Span(t testing.TB, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+
+	spans := r.SpansByName(name)
+	if len(spans) == 0 {
+		t.Fatalf("telemetrytest: no span %q recorded, got %v", name, spanNames(r.spans.Ended()))
+	}
+	return spans[0]
+}
+
+// Metrics collects the current value of every instrument.
+func (r *Recorder) Metrics(t testing.TB) metricdata.ResourceMetrics {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	if err := r.reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("telemetrytest: collect metrics: %v", err)
+	}
+	return rm
+}
+
+// Metric collects the metrics and returns the first one called name,
+// failing the test when there is none.
+func (r *Recorder) Metric(t testing.TB, name string) metricdata.Metrics {
+	t.Helper()
+
+	rm := r.Metrics(t)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m
+			}
+		}
+	}
+	t.Fatalf("telemetrytest: no metric %q recorded", name)
+	return metricdata.Metrics{}
+}
+
+// Logs returns the emitted log records in the order they were emitted.
+func (r *Recorder) Logs() []log.Record {
+	return r.logs.records()
+}
+
+// logRecorder is a log processor keeping a copy of every record.
+type logRecorder struct {
+	mu   sync.Mutex
+	logs []log.Record
+}
+
+func (l *logRecorder) OnEmit(_ context.Context, record *log.Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.logs = append(l.logs, record.Clone())
+	return nil
+}
+
+func (l *logRecorder) ForceFlush(context.Context) error { return nil }
+
+func (l *logRecorder) Shutdown(context.Context) error { return nil }
+
+func (l *logRecorder) records() []log.Record {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]log.Record(nil), l.logs...)
+}
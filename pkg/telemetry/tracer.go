@@ -0,0 +1,56 @@
+package telemetry
+
+import (
+	"context"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+)
+
+var (
+	// tracer backs StartSpan and Do. It is usable before SetupOTelSDK runs,
+	// producing no-op spans until a TracerProvider is installed.
+	tracer = NewTracer(instrumentationName, oteltrace.WithInstrumentationVersion(buildVersion()))
+)
+
+// NewTracer returns a tracer for the instrumented package name that looks up
+// the global TracerProvider on every span start. Unlike a tracer from
+// otel.Tracer it follows every provider installed after it is created, so it
+// can be declared as a package variable and survives re-initialisation in
+// tests.
+func NewTracer(name string, opts ...oteltrace.TracerOption) oteltrace.Tracer {
+	return &globalTracer{name: name, opts: opts}
+}
+
+type globalTracer struct {
+	embedded.Tracer
+
+	name string
+	opts []oteltrace.TracerOption
+	// resolved caches the tracer of the last provider seen.
+	resolved atomic.Pointer[resolvedTracer]
+}
+
+// resolvedTracer is the tracer obtained from provider.
+type resolvedTracer struct {
+	provider oteltrace.TracerProvider
+	tracer   oteltrace.Tracer
+}
+
+func (t *globalTracer) Start(ctx context.Context, spanName string, opts ...oteltrace.SpanStartOption) (context.Context, oteltrace.Span) {
+	return t.resolve().Start(ctx, spanName, opts...)
+}
+
+// resolve returns the tracer of the global TracerProvider, obtaining it again
+// only after another provider is installed.
+func (t *globalTracer) resolve() oteltrace.Tracer {
+	provider := otel.GetTracerProvider()
+	if resolved := t.resolved.Load(); resolved != nil && resolved.provider == provider {
+		return resolved.tracer
+	}
+	tracer := provider.Tracer(t.name, t.opts...)
+	t.resolved.Store(&resolvedTracer{provider: provider, tracer: tracer})
+	return tracer
+}
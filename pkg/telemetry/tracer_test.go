@@ -0,0 +1,33 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestNewTracerFollowsSetup(t *testing.T) {
+	// Created before setup, as package variables are.
+	tracer := NewTracer("tracer-test")
+	before := otel.GetTracerProvider()
+
+	for _, setup := range []string{"first", "second"} {
+		t.Run(setup, func(t *testing.T) {
+			spans := tracetest.NewSpanRecorder()
+			setupRuntime(t, WithSampler(trace.AlwaysSample()), WithSpanProcessor(spans))
+
+			_, span := tracer.Start(context.Background(), setup)
+			span.End()
+			if ended := spans.Ended(); len(ended) != 1 || ended[0].Name() != setup {
+				t.Errorf("spans = %v, want %s", ended, setup)
+			}
+		})
+	}
+
+	if otel.GetTracerProvider() != before {
+		t.Errorf("tracer provider not restored after shutdown")
+	}
+}